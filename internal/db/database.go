@@ -15,10 +15,29 @@ var DB *gorm.DB
 // InitDB initializes the database connection using GORM and performs schema migrations for the given models.
 func InitDB() {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("void.db"), &gorm.Config{})
+	DB, err = Open("void.db")
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+}
+
+// Open connects to the SQLite database at dsn and brings its schema up to date. Tests use it with
+// an in-memory database, such as "file:name?mode=memory&cache=shared".
+func Open(dsn string) (*gorm.DB, error) {
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Accounts that predate email verification are trusted, so mark them verified when the column is added.
+	backfillVerified := conn.Migrator().HasTable(&models.User{}) && !conn.Migrator().HasColumn(&models.User{}, "verified")
+
+	conn.AutoMigrate(&models.Shout{}, &models.Echo{}, &models.User{}, &models.Notification{}, &models.Follow{}, &models.Mention{}, &models.Tag{}, &models.ShoutTag{}, &models.AccessToken{}, &models.PasswordResetToken{}, &models.RecoveryCode{}, &models.LoginAttempt{}, &models.UserSession{}, &models.UserIdentity{}, &models.Setting{}, &models.Invite{}, &models.Report{}, &models.Block{}, &models.Mute{})
+
+	if backfillVerified {
+		conn.Model(&models.User{}).Where("1 = 1").Update("verified", true)
+	}
 
-	DB.AutoMigrate(&models.Shout{}, &models.Echo{}, &models.User{}, &models.Notification{})
+	migrateSearch(conn)
+	return conn, nil
 }
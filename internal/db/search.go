@@ -0,0 +1,64 @@
+package db
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// SearchEnabled reports whether the FTS5 search indexes were created. It is false when the
+// SQLite driver was built without FTS5 support (build with `-tags sqlite_fts5`).
+var SearchEnabled bool
+
+// searchIndexes describes each FTS5 index: the indexed table and the columns kept in sync with it.
+var searchIndexes = []struct {
+	table   string
+	columns []string
+}{
+	{table: "shouts", columns: []string{"content"}},
+	{table: "echos", columns: []string{"content"}},
+	{table: "users", columns: []string{"username", "bio"}},
+}
+
+// migrateSearch creates the FTS5 virtual tables and the triggers that keep them in sync with
+// their source tables, rebuilding an index from existing rows the first time it is created.
+func migrateSearch(db *gorm.DB) {
+	for _, index := range searchIndexes {
+		fts := index.table + "_fts"
+
+		var existing int64
+		db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", fts).Scan(&existing)
+
+		cols, newCols, oldCols := "", "", ""
+		for i, column := range index.columns {
+			if i > 0 {
+				cols, newCols, oldCols = cols+", ", newCols+", ", oldCols+", "
+			}
+			cols += column
+			newCols += "new." + column
+			oldCols += "old." + column
+		}
+
+		statements := []string{
+			"CREATE VIRTUAL TABLE IF NOT EXISTS " + fts + " USING fts5(" + cols + ", content='" + index.table + "', content_rowid='id')",
+			"CREATE TRIGGER IF NOT EXISTS " + index.table + "_fts_ai AFTER INSERT ON " + index.table + " BEGIN " +
+				"INSERT INTO " + fts + "(rowid, " + cols + ") VALUES (new.id, " + newCols + "); END",
+			"CREATE TRIGGER IF NOT EXISTS " + index.table + "_fts_ad AFTER DELETE ON " + index.table + " BEGIN " +
+				"INSERT INTO " + fts + "(" + fts + ", rowid, " + cols + ") VALUES ('delete', old.id, " + oldCols + "); END",
+			"CREATE TRIGGER IF NOT EXISTS " + index.table + "_fts_au AFTER UPDATE ON " + index.table + " BEGIN " +
+				"INSERT INTO " + fts + "(" + fts + ", rowid, " + cols + ") VALUES ('delete', old.id, " + oldCols + "); " +
+				"INSERT INTO " + fts + "(rowid, " + cols + ") VALUES (new.id, " + newCols + "); END",
+		}
+		if existing == 0 {
+			statements = append(statements, "INSERT INTO "+fts+"("+fts+") VALUES ('rebuild')")
+		}
+
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				log.Printf("Search disabled: failed to set up %s: %v", fts, err)
+				return
+			}
+		}
+	}
+	SearchEnabled = true
+}
@@ -1,5 +1,19 @@
 package events
 
+import "encoding/json"
+
+// Event types carried in an Envelope so consumers know how to decode the payload.
+const (
+	TypeShoutCreated = "shout_created"
+	TypeEchoCreated  = "echo_created"
+)
+
+// Envelope wraps an event payload with its type so that several kinds of events can share a queue.
+type Envelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
 // ShoutEvent is an interface representing the data needed for a shout event.
 type ShoutEvent interface {
 	GetShoutID() uint
@@ -7,3 +21,13 @@ type ShoutEvent interface {
 	GetUserID() uint
 	GetUsername() string
 }
+
+// EchoEvent is an interface representing the data needed for an echo event.
+type EchoEvent interface {
+	GetEchoID() uint
+	GetShoutID() uint
+	GetShoutAuthorID() uint
+	GetContent() string
+	GetUserID() uint
+	GetUsername() string
+}
@@ -7,18 +7,58 @@ import (
 	"Void/pkg/rabbitmq"
 )
 
+// Transport delivers encoded event messages. It sends them to RabbitMQ's notification queue unless
+// replaced, as tests do to run without a broker.
+var Transport = rabbitmq.PublishNotification
+
 // PublishShoutEvent publishes a shout event.
 // It depends solely on the ShoutEvent interface.
 func PublishShoutEvent(event ShoutEvent) error {
-	msg, err := json.Marshal(event)
+	if err := publish(TypeShoutCreated, event); err != nil {
+		return err
+	}
+	log.Printf("Published shout event for shout ID: %d", event.GetShoutID())
+	return nil
+}
+
+// PublishEchoEvent publishes an echo event.
+// It depends solely on the EchoEvent interface.
+func PublishEchoEvent(event EchoEvent) error {
+	if err := publish(TypeEchoCreated, event); err != nil {
+		return err
+	}
+	log.Printf("Published echo event for echo ID: %d", event.GetEchoID())
+	return nil
+}
+
+// publish wraps the event in an Envelope of the given type and sends it with Transport.
+func publish(eventType string, event any) error {
+	payload, err := json.Marshal(event)
 	if err != nil {
 		log.Printf("Failed to marshal event: %v", err)
 		return err
 	}
-	if err := rabbitmq.PublishNotification(msg); err != nil {
+	msg, err := json.Marshal(Envelope{Type: eventType, Payload: payload})
+	if err != nil {
+		log.Printf("Failed to marshal envelope: %v", err)
+		return err
+	}
+	if err := Transport(msg); err != nil {
 		log.Printf("Failed to publish event: %v", err)
 		return err
 	}
-	log.Printf("Published shout event for shout ID: %d", event.GetShoutID())
 	return nil
 }
+
+// Decode splits a raw queue message into its event type and payload.
+// Messages published before events were wrapped in an Envelope are treated as shout events.
+func Decode(body []byte) (string, json.RawMessage, error) {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return "", nil, err
+	}
+	if env.Type == "" {
+		return TypeShoutCreated, body, nil
+	}
+	return env.Type, env.Payload, nil
+}
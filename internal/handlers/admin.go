@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/policy"
+	"Void/pkg/mailer"
+	"Void/pkg/rabbitmq"
+)
+
+// adminActivityDays is how many days of activity the admin dashboard charts.
+const adminActivityDays = 14
+
+// adminRecentLimit is how many recent shouts and echoes the admin dashboard lists.
+const adminRecentLimit = 20
+
+// adminUserLimit is how many users one page of the admin user list shows.
+const adminUserLimit = 50
+
+// adminNotices are the messages the admin user list shows after an action, keyed by the "notice" query parameter.
+var adminNotices = map[string]string{
+	"role":   "Role updated.",
+	"status": "Account status updated. Suspended and banned users have been signed out.",
+	"reset":  "Password reset. The user has been signed out and emailed a link to choose a new password.",
+}
+
+// RegisterAdminRoutes registers the instance administration routes, which only admins can use.
+func RegisterAdminRoutes(app *fiber.App) {
+	admin := app.Group("/admin", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireRole(models.RoleAdmin))
+	admin.Get("/", ShowAdminDashboard)
+	admin.Post("/shouts/:id/delete", AdminDeleteShout)
+	admin.Post("/echoes/:id/delete", AdminDeleteEcho)
+	admin.Get("/users", ShowAdminUsers)
+	admin.Post("/users/:id/role", UpdateUserRole)
+	admin.Post("/users/:id/status", UpdateUserStatus)
+	admin.Post("/users/:id/reset-password", AdminResetPassword)
+	admin.Get("/registrations", ShowRegistrations)
+	admin.Post("/registrations/mode", UpdateRegistrationMode)
+	admin.Post("/registrations/:id/approve", ApproveRegistration)
+	admin.Post("/registrations/:id/reject", RejectRegistration)
+}
+
+// ShowRegistrations renders the registration mode setting and the queue of accounts waiting for approval.
+func ShowRegistrations(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	mode, err := models.GetRegistrationMode(db.DB)
+	if err != nil {
+		return c.Status(500).SendString("Error loading registration settings")
+	}
+	pending, err := models.PendingApprovals(db.DB)
+	if err != nil {
+		log.Printf("Error fetching pending registrations: %v", err)
+		return c.Status(500).SendString("Error loading registrations")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("admin_registrations", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Mode":              mode,
+		"Modes":             models.RegistrationModes,
+		"Pending":           pending,
+	}, "layouts/main")
+}
+
+// UpdateRegistrationMode changes who may register.
+func UpdateRegistrationMode(c *fiber.Ctx) error {
+	if err := models.SetRegistrationMode(db.DB, c.FormValue("mode")); err != nil {
+		if models.IsValidationError(err) {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+		}
+		log.Printf("Error updating registration mode: %v", err)
+		return c.Status(500).SendString("Error updating registration mode")
+	}
+	return c.Redirect("/admin/registrations")
+}
+
+// ApproveRegistration lets a user waiting for approval log in, and emails them.
+func ApproveRegistration(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(400).SendString("Invalid user")
+	}
+	user, err := models.ApproveUser(db.DB, uint(id))
+	if err != nil {
+		return c.Status(404).SendString("No pending registration for that user")
+	}
+
+	go sendRegistrationDecisionEmail(*user, "Your Void account has been approved",
+		fmt.Sprintf("Hi %s,\n\nAn admin has approved your Void account. You can log in now:\n\n%s\n",
+			user.Username, absoluteURL("/login")))
+	return c.Redirect("/admin/registrations")
+}
+
+// RejectRegistration deletes a user waiting for approval, and emails them.
+func RejectRegistration(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(400).SendString("Invalid user")
+	}
+	user, err := models.RejectUser(db.DB, uint(id))
+	if err != nil {
+		return c.Status(404).SendString("No pending registration for that user")
+	}
+
+	go sendRegistrationDecisionEmail(*user, "Your Void registration",
+		fmt.Sprintf("Hi %s,\n\nSorry, your request for a Void account was not approved, and the account has been removed.\n",
+			user.Username))
+	return c.Redirect("/admin/registrations")
+}
+
+// sendRegistrationDecisionEmail tells a user the outcome of their registration, logging any failure.
+func sendRegistrationDecisionEmail(user models.User, subject, body string) {
+	if err := mailer.Send(mailer.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		log.Printf("Error sending registration email to user %d: %v", user.ID, err)
+	}
+}
+
+// ShowAdminDashboard renders instance totals, daily activity, the notification queue's depth and
+// the most recent shouts and echoes.
+func ShowAdminDashboard(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	totals, err := models.GetInstanceTotals(db.DB)
+	if err != nil {
+		log.Printf("Error counting instance totals: %v", err)
+		return c.Status(500).SendString("Error loading dashboard")
+	}
+	activity, err := models.RecentActivity(db.DB, adminActivityDays)
+	if err != nil {
+		log.Printf("Error counting recent activity: %v", err)
+		return c.Status(500).SendString("Error loading dashboard")
+	}
+
+	var shouts []models.Shout
+	var echoes []models.Echo
+	if err := db.DB.Preload("User").Order("created_at DESC").Limit(adminRecentLimit).Find(&shouts).Error; err != nil {
+		log.Printf("Error fetching recent shouts: %v", err)
+		return c.Status(500).SendString("Error loading dashboard")
+	}
+	if err := db.DB.Preload("User").Order("created_at DESC").Limit(adminRecentLimit).Find(&echoes).Error; err != nil {
+		log.Printf("Error fetching recent echoes: %v", err)
+		return c.Status(500).SendString("Error loading dashboard")
+	}
+
+	// The dashboard still renders when RabbitMQ is unreachable; that is worth seeing.
+	queueError := ""
+	messages, consumers, err := rabbitmq.QueueDepth()
+	if err != nil {
+		log.Printf("Error inspecting notification queue: %v", err)
+		queueError = err.Error()
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("admin_dashboard", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Totals":            totals,
+		"Activity":          activity,
+		"QueueMessages":     messages,
+		"QueueConsumers":    consumers,
+		"QueueError":        queueError,
+		"Shouts":            shouts,
+		"Echoes":            echoes,
+	}, "layouts/main")
+}
+
+// AdminDeleteShout deletes any shout from the admin dashboard.
+func AdminDeleteShout(c *fiber.Ctx) error {
+	var shout models.Shout
+	if err := db.DB.First(&shout, c.Params("id")).Error; err != nil {
+		return c.Status(404).SendString("Shout not found")
+	}
+	if !can(c, policy.DeleteShout, shout) {
+		return c.Status(403).SendString("You don't have permission to do that")
+	}
+	if err := shout.Delete(db.DB); err != nil {
+		log.Printf("Error deleting shout %d: %v", shout.ID, err)
+		return c.Status(500).SendString("Error deleting shout")
+	}
+	return c.Redirect("/admin")
+}
+
+// AdminDeleteEcho deletes any echo from the admin dashboard.
+func AdminDeleteEcho(c *fiber.Ctx) error {
+	var echo models.Echo
+	if err := db.DB.First(&echo, c.Params("id")).Error; err != nil {
+		return c.Status(404).SendString("Echo not found")
+	}
+	if !can(c, policy.DeleteEcho, echo) {
+		return c.Status(403).SendString("You don't have permission to do that")
+	}
+	if err := echo.Delete(db.DB); err != nil {
+		log.Printf("Error deleting echo %d: %v", echo.ID, err)
+		return c.Status(500).SendString("Error deleting echo")
+	}
+	return c.Redirect("/admin")
+}
+
+// ShowAdminUsers renders the newest users, optionally filtered by a search of usernames and emails.
+func ShowAdminUsers(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	q := strings.TrimSpace(c.Query("q"))
+
+	users, err := models.SearchUsers(db.DB, q, adminUserLimit)
+	if err != nil {
+		log.Printf("Error fetching users: %v", err)
+		return c.Status(500).SendString("Error loading users")
+	}
+	actor, err := currentUser(c)
+	if err != nil {
+		return c.SendString("User not found")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("admin_users", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Actor":             actor,
+		"Query":             q,
+		"Users":             users,
+		"Roles":             models.Roles,
+		"Statuses":          models.UserStatuses,
+		"Limit":             adminUserLimit,
+		"Notice":            adminNotices[c.Query("notice")],
+	}, "layouts/main")
+}
+
+// UpdateUserRole changes another user's role.
+func UpdateUserRole(c *fiber.Ctx) error {
+	user, err := adminTargetUser(c)
+	if err != nil {
+		return err
+	}
+	if err := user.SetRole(db.DB, c.FormValue("role")); err != nil {
+		if models.IsValidationError(err) {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+		}
+		log.Printf("Error updating role of user %d: %v", user.ID, err)
+		return c.Status(500).SendString("Error updating role")
+	}
+	return redirectToAdminUsers(c, "role")
+}
+
+// UpdateUserStatus sets another user's account status from the "status" form field. Suspensions
+// last for the number of days in the "days" field.
+func UpdateUserStatus(c *fiber.Ctx) error {
+	user, err := adminTargetUser(c)
+	if err != nil {
+		return err
+	}
+	if !can(c, policy.RestrictUser, user) {
+		return c.Status(403).SendString("You can't change this user's status")
+	}
+
+	status := c.FormValue("status")
+	var until time.Time
+	if status == models.StatusSuspended {
+		days, err := strconv.Atoi(c.FormValue("days"))
+		if err != nil || days <= 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString("Enter the number of days to suspend for")
+		}
+		until = time.Now().AddDate(0, 0, days)
+	}
+	if err := models.SetUserStatus(db.DB, user.ID, status, until); err != nil {
+		if models.IsValidationError(err) {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+		}
+		log.Printf("Error updating status of user %d: %v", user.ID, err)
+		return c.Status(500).SendString("Error updating status")
+	}
+	return redirectToAdminUsers(c, "status")
+}
+
+// AdminResetPassword locks another user's password, signs them out everywhere and emails them a
+// password reset link, for accounts that may be compromised. Like other restrictions, it only
+// applies to users the admin outranks.
+func AdminResetPassword(c *fiber.Ctx) error {
+	user, err := adminTargetUser(c)
+	if err != nil {
+		return err
+	}
+	if !can(c, policy.RestrictUser, user) {
+		return c.Status(403).SendString("You can't reset this user's password")
+	}
+	if err := models.LockPassword(db.DB, user.ID); err != nil {
+		log.Printf("Error locking password of user %d: %v", user.ID, err)
+		return c.Status(500).SendString("Error resetting password")
+	}
+	token, err := models.NewPasswordResetToken(db.DB, user.ID)
+	if err != nil {
+		log.Printf("Error creating password reset token for user %d: %v", user.ID, err)
+		return c.Status(500).SendString("Error resetting password")
+	}
+	go sendPasswordResetEmail(*user, absoluteURL("/reset-password?token="+url.QueryEscape(token)))
+	return redirectToAdminUsers(c, "reset")
+}
+
+// adminTargetUser loads the user named by the :id route parameter. Admins cannot act on their own
+// account here, so they cannot lock themselves out by accident.
+func adminTargetUser(c *fiber.Ctx) (*models.User, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user")
+	}
+	if uint(id) == c.Locals("UserID").(uint) {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, "You can't change your own account from the admin pages")
+	}
+	var user models.User
+	if err := db.DB.First(&user, id).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	return &user, nil
+}
+
+// redirectToAdminUsers returns to the admin user list, keeping the search from the submitted form
+// and showing the notice for the action just taken.
+func redirectToAdminUsers(c *fiber.Ctx, notice string) error {
+	return c.Redirect("/admin/users?q=" + url.QueryEscape(c.FormValue("q")) + "&notice=" + notice)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/pagination"
+)
+
+// RegisterAPIRoutes registers the versioned JSON API under /api/v1. Every response uses the same
+// envelope: {"data": ...} on success, with "pagination" links for lists, and
+// {"error": {"status": ..., "message": ...}} on failure. Requests may authenticate with the session
+// cookie or with a personal access token, whose scopes limit what it can do. Routes added here must
+// also be documented in apiOperations, which the OpenAPI document at /api/openapi.json is built from.
+func RegisterAPIRoutes(app *fiber.App) {
+	app.Get("/api/openapi.json", GetOpenAPISpec)
+
+	api := app.Group("/api/v1", middleware.GetUserFromSession, middleware.GetUserFromToken)
+
+	read := middleware.RequireScope(models.ScopeRead)
+	write := middleware.RequireScope(models.ScopeWrite)
+	notifications := middleware.RequireScope(models.ScopeNotifications)
+
+	// Public, read-only endpoints.
+	api.Get("/users/:username", read, APIGetUser)
+	api.Get("/users/:username/shouts", read, APIGetUserShouts)
+	api.Get("/shouts", read, APIGetShouts)
+	api.Get("/shouts/:id", read, APIGetShout)
+	api.Get("/shouts/:id/echoes", read, APIGetEchoes)
+
+	// Endpoints that act on behalf of the logged-in user, by session or by access token.
+	api.Get("/me", middleware.RequireAPILogin, read, APIGetMe)
+	api.Get("/timeline", middleware.RequireAPILogin, read, APIGetTimeline)
+	api.Post("/shouts", middleware.RequireAPILogin, write, middleware.RequireAPIVerified, APICreateShout)
+	api.Patch("/shouts/:id", middleware.RequireAPILogin, write, APIUpdateShout)
+	api.Delete("/shouts/:id", middleware.RequireAPILogin, write, APIDeleteShout)
+	api.Post("/shouts/:id/echoes", middleware.RequireAPILogin, write, middleware.RequireAPIVerified, APICreateEcho)
+	api.Delete("/echoes/:id", middleware.RequireAPILogin, write, APIDeleteEcho)
+	api.Get("/notifications", middleware.RequireAPILogin, notifications, APIGetNotifications)
+	api.Post("/notifications/:id/read", middleware.RequireAPILogin, notifications, APIMarkNotificationAsRead)
+
+	// Anything else under /api/v1 is a JSON 404 rather than an HTML page.
+	api.Use(func(c *fiber.Ctx) error {
+		return apiError(c, fiber.StatusNotFound, "no such endpoint")
+	})
+}
+
+// APIUser is the public JSON representation of a models.User.
+type APIUser struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIProfile is an APIUser together with their follow counts.
+type APIProfile struct {
+	APIUser
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+}
+
+// APIShout is the JSON representation of a models.Shout.
+type APIShout struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	Author    APIUser   `json:"author"`
+	EchoCount int64     `json:"echo_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// APIEcho is the JSON representation of a models.Echo.
+type APIEcho struct {
+	ID        uint      `json:"id"`
+	ShoutID   uint      `json:"shout_id"`
+	Content   string    `json:"content"`
+	Author    *APIUser  `json:"author"` // Null for echoes created before authors were tracked
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APINotification is the JSON representation of a models.Notification.
+type APINotification struct {
+	ID             uint      `json:"id"`
+	Type           string    `json:"type"`
+	Message        string    `json:"message"`
+	AuthorUsername string    `json:"author_username"`
+	AuthorAvatar   string    `json:"author_avatar"`
+	ShoutID        uint      `json:"shout_id"`
+	EchoID         uint      `json:"echo_id"`
+	Read           bool      `json:"read"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// APIPagination holds links to the neighbouring pages of a list; null means there is no such page.
+type APIPagination struct {
+	Older *string `json:"older"`
+	Newer *string `json:"newer"`
+}
+
+// contentRequest is the request body for creating or updating a shout or echo.
+type contentRequest struct {
+	Content string `json:"content" form:"content"`
+}
+
+func toAPIUser(u models.User) APIUser {
+	return APIUser{ID: u.ID, Username: u.Username, Avatar: u.Avatar, Bio: u.Bio, CreatedAt: u.CreatedAt}
+}
+
+func toAPIShout(s models.Shout) APIShout {
+	return APIShout{
+		ID:        s.ID,
+		Content:   s.Content,
+		Author:    toAPIUser(s.User),
+		EchoCount: s.EchoCount,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+func toAPIEcho(e models.Echo) APIEcho {
+	echo := APIEcho{ID: e.ID, ShoutID: e.ShoutID, Content: e.Content, CreatedAt: e.CreatedAt}
+	if e.UserID != 0 {
+		author := toAPIUser(e.User)
+		echo.Author = &author
+	}
+	return echo
+}
+
+func toAPINotification(n models.Notification) APINotification {
+	return APINotification{
+		ID:             n.ID,
+		Type:           n.Type,
+		Message:        n.Message,
+		AuthorUsername: n.AuthorUsername,
+		AuthorAvatar:   n.AuthorAvatar,
+		ShoutID:        n.ShoutID,
+		EchoID:         n.EchoID,
+		Read:           n.Read,
+		CreatedAt:      n.CreatedAt,
+	}
+}
+
+// apiData writes a successful response envelope.
+func apiData(c *fiber.Ctx, status int, data interface{}) error {
+	return c.Status(status).JSON(fiber.Map{"data": data})
+}
+
+// apiList writes a successful response envelope for one page of a list.
+func apiList(c *fiber.Ctx, data interface{}, page pagination.Page) error {
+	var links APIPagination
+	if page.Older != "" {
+		older := c.Path() + page.Older
+		links.Older = &older
+	}
+	if page.Newer != "" {
+		newer := c.Path() + page.Newer
+		links.Newer = &newer
+	}
+	return c.JSON(fiber.Map{"data": data, "pagination": links})
+}
+
+// apiError writes an error response envelope.
+func apiError(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": fiber.Map{
+			"status":  status,
+			"message": message,
+		},
+	})
+}
+
+// apiFail maps an error to an error response: *fiber.Error keeps its status and message,
+// model validation failures become 422s, and anything else is logged and reported as a 500.
+func apiFail(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return apiError(c, fiberErr.Code, fiberErr.Message)
+	}
+	if models.IsValidationError(err) {
+		return apiError(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	log.Printf("API error on %s %s: %v", c.Method(), c.Path(), err)
+	return apiError(c, fiber.StatusInternalServerError, "internal server error")
+}
+
+// apiFindPage loads one page of query for the request's cursor parameters.
+func apiFindPage[T any](c *fiber.Ctx, query *gorm.DB, cursorOf func(T) pagination.Cursor) ([]T, pagination.Page, error) {
+	req, err := pagination.FromRequest(c)
+	if err != nil {
+		return nil, pagination.Page{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return pagination.Find(query, req, cursorOf)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+	"Void/internal/policy"
+)
+
+// APIGetNotifications returns a page of the logged-in user's notifications, newest first.
+// Pass ?unread=true to only include unread notifications.
+func APIGetNotifications(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	query := db.DB.Where("user_id = ?", uid)
+	if c.QueryBool("unread") {
+		query = query.Where("read = ?", false)
+	}
+
+	notifications, page, err := apiFindPage(c, query, models.Notification.Cursor)
+	if err != nil {
+		return apiFail(c, err)
+	}
+
+	data := make([]APINotification, len(notifications))
+	for i, notification := range notifications {
+		data[i] = toAPINotification(notification)
+	}
+	return apiList(c, data, page)
+}
+
+// APIMarkNotificationAsRead marks one of the logged-in user's notifications as read and returns it.
+func APIMarkNotificationAsRead(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return apiError(c, fiber.StatusBadRequest, "invalid notification id")
+	}
+
+	var notif models.Notification
+	if err := db.DB.First(&notif, id).Error; err != nil {
+		return apiError(c, fiber.StatusNotFound, "notification not found")
+	}
+	if !can(c, policy.ReadNotification, notif) {
+		return apiError(c, fiber.StatusForbidden, "access denied")
+	}
+
+	notif.Read = true
+	if err := db.DB.Save(&notif).Error; err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusOK, toAPINotification(notif))
+}
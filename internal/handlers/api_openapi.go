@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/models"
+	"Void/internal/openapi"
+	"Void/internal/pagination"
+)
+
+// apiOperation documents one /api/v1 route for the OpenAPI document.
+type apiOperation struct {
+	ID       string
+	Summary  string
+	Tag      string
+	Login    bool                // Requires a session or access token
+	Scope    string              // Access token scope the route requires
+	List     bool                // Paginated list: accepts cursor parameters and returns pagination links
+	Query    []openapi.Parameter // Query parameters besides the pagination ones
+	Body     interface{}         // Request body type, if any
+	Status   int                 // Success status; defaults to 200
+	Response interface{}         // Type of the "data" member; nil for responses without a body
+	Errors   []int               // Error statuses besides those implied by Login, Scope, List and Body
+}
+
+// apiOperations documents every route registered by RegisterAPIRoutes, keyed by method and Fiber path.
+// APISpec fails if this table and the router disagree, and TestAPISpecMatchesRouter checks that they
+// agree, so add new endpoints to both.
+var apiOperations = map[string]apiOperation{
+	"GET /api/v1/users/:username": {
+		ID: "getUser", Summary: "Get a user's profile", Tag: "users",
+		Scope: models.ScopeRead, Response: APIProfile{}, Errors: []int{http.StatusNotFound},
+	},
+	"GET /api/v1/users/:username/shouts": {
+		ID: "listUserShouts", Summary: "List a user's shouts, newest first", Tag: "users",
+		Scope: models.ScopeRead, List: true, Response: APIShout{}, Errors: []int{http.StatusNotFound},
+	},
+	"GET /api/v1/shouts": {
+		ID: "listShouts", Summary: "List every shout, newest first", Tag: "shouts",
+		Scope: models.ScopeRead, List: true, Response: APIShout{},
+	},
+	"GET /api/v1/shouts/:id": {
+		ID: "getShout", Summary: "Get a shout", Tag: "shouts",
+		Scope: models.ScopeRead, Response: APIShout{}, Errors: []int{http.StatusNotFound},
+	},
+	"GET /api/v1/shouts/:id/echoes": {
+		ID: "listEchoes", Summary: "List a shout's echoes, newest first", Tag: "shouts",
+		Scope: models.ScopeRead, List: true, Response: APIEcho{}, Errors: []int{http.StatusNotFound},
+	},
+	"GET /api/v1/me": {
+		ID: "getMe", Summary: "Get the authenticated user's profile", Tag: "users",
+		Login: true, Scope: models.ScopeRead, Response: APIProfile{},
+	},
+	"GET /api/v1/timeline": {
+		ID: "getTimeline", Summary: "List the authenticated user's home timeline, newest first", Tag: "shouts",
+		Login: true, Scope: models.ScopeRead, List: true, Response: APIShout{},
+	},
+	"POST /api/v1/shouts": {
+		ID: "createShout", Summary: "Create a shout", Tag: "shouts",
+		Login: true, Scope: models.ScopeWrite, Body: contentRequest{},
+		Status: http.StatusCreated, Response: APIShout{},
+	},
+	"PATCH /api/v1/shouts/:id": {
+		ID: "updateShout", Summary: "Edit one of the authenticated user's shouts", Tag: "shouts",
+		Login: true, Scope: models.ScopeWrite, Body: contentRequest{},
+		Response: APIShout{}, Errors: []int{http.StatusNotFound},
+	},
+	"DELETE /api/v1/shouts/:id": {
+		ID: "deleteShout", Summary: "Delete one of the authenticated user's shouts, or any shout as a moderator", Tag: "shouts",
+		Login: true, Scope: models.ScopeWrite,
+		Status: http.StatusNoContent, Errors: []int{http.StatusNotFound},
+	},
+	"POST /api/v1/shouts/:id/echoes": {
+		ID: "createEcho", Summary: "Echo a shout", Tag: "shouts",
+		Login: true, Scope: models.ScopeWrite, Body: contentRequest{},
+		Status: http.StatusCreated, Response: APIEcho{}, Errors: []int{http.StatusNotFound},
+	},
+	"DELETE /api/v1/echoes/:id": {
+		ID: "deleteEcho", Summary: "Delete one of the authenticated user's echoes, or any echo as a moderator", Tag: "shouts",
+		Login: true, Scope: models.ScopeWrite,
+		Status: http.StatusNoContent, Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	"GET /api/v1/notifications": {
+		ID: "listNotifications", Summary: "List the authenticated user's notifications, newest first", Tag: "notifications",
+		Login: true, Scope: models.ScopeNotifications, List: true, Response: APINotification{},
+		Query: []openapi.Parameter{{
+			Name: "unread", In: "query", Description: "Only include unread notifications",
+			Schema: &openapi.Schema{Type: "boolean"},
+		}},
+	},
+	"POST /api/v1/notifications/:id/read": {
+		ID: "markNotificationRead", Summary: "Mark a notification as read", Tag: "notifications",
+		Login: true, Scope: models.ScopeNotifications,
+		Response: APINotification{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+}
+
+// apiErrorBody is the error envelope written by apiError.
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+// apiErrorDetail is the "error" member of apiErrorBody.
+type apiErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+var (
+	apiSpecOnce sync.Once
+	apiSpecJSON []byte
+	apiSpecErr  error
+)
+
+// GetOpenAPISpec serves the OpenAPI document for the JSON API. It is built from the live router
+// the first time it is requested.
+func GetOpenAPISpec(c *fiber.Ctx) error {
+	apiSpecOnce.Do(func() {
+		var doc *openapi.Document
+		if doc, apiSpecErr = APISpec(c.App()); apiSpecErr == nil {
+			apiSpecJSON, apiSpecErr = json.Marshal(doc)
+		}
+	})
+	if apiSpecErr != nil {
+		return apiFail(c, apiSpecErr)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(apiSpecJSON)
+}
+
+// fiberParam matches a Fiber route parameter such as ":id".
+var fiberParam = regexp.MustCompile(`:(\w+)`)
+
+// APISpec builds the OpenAPI document for every /api/v1 route registered on app. It returns an
+// error if a route is missing from apiOperations, or if apiOperations documents a route that is
+// not registered, so the document cannot drift from the router.
+func APISpec(app *fiber.App) (*openapi.Document, error) {
+	doc := openapi.New("Void API", "1.0.0")
+	doc.Info.Description = "Every response uses the same envelope: {\"data\": ...} on success, with " +
+		"\"pagination\" links for lists, and {\"error\": {\"status\": ..., \"message\": ...}} on failure."
+	doc.Servers = []openapi.Server{{URL: "/"}}
+	doc.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "A personal access token, created on the Edit Profile page. Its scopes limit which operations it may call.",
+	}
+	doc.Components.SecuritySchemes["cookieAuth"] = openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        "session_id",
+		Description: "The browser session of a logged-in user. Sessions are not limited by scopes, but state-changing requests must send the page's CSRF token in the X-Csrf-Token header.",
+	}
+
+	errorSchema := doc.SchemaOf(apiErrorBody{})
+	paginationSchema := doc.SchemaOf(APIPagination{})
+
+	seen := map[string]bool{}
+	var problems []string
+	for _, route := range app.GetRoutes(true) {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Method == fiber.MethodHead {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		spec, ok := apiOperations[key]
+		if !ok {
+			problems = append(problems, "undocumented route "+key)
+			continue
+		}
+		path := fiberParam.ReplaceAllString(route.Path, "{$1}")
+		doc.AddOperation(route.Method, path, spec.operation(doc, route.Params, errorSchema, paginationSchema))
+	}
+	for key := range apiOperations {
+		if !seen[key] {
+			problems = append(problems, "documented route "+key+" is not registered")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("OpenAPI document does not match the router: %s", strings.Join(problems, "; "))
+	}
+	return doc, nil
+}
+
+// operation converts the table entry into an OpenAPI operation for a route with the given path parameters.
+func (o apiOperation) operation(doc *openapi.Document, params []string, errorSchema, paginationSchema *openapi.Schema) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: o.ID,
+		Summary:     o.Summary,
+		Tags:        []string{o.Tag},
+		Responses:   map[string]openapi.Response{},
+	}
+	errorStatuses := append([]int(nil), o.Errors...)
+
+	for _, name := range params {
+		schema := &openapi.Schema{Type: "string"}
+		if name == "id" {
+			schema = &openapi.Schema{Type: "integer", Format: "int64"}
+		}
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	if o.List {
+		minimum, maximum := 1, pagination.MaxPageSize
+		op.Parameters = append(op.Parameters,
+			openapi.Parameter{Name: "before", In: "query", Description: "Cursor from an \"older\" pagination link", Schema: &openapi.Schema{Type: "string"}},
+			openapi.Parameter{Name: "after", In: "query", Description: "Cursor from a \"newer\" pagination link", Schema: &openapi.Schema{Type: "string"}},
+			openapi.Parameter{Name: "limit", In: "query", Description: "Page size, " + strconv.Itoa(pagination.DefaultPageSize) + " by default", Schema: &openapi.Schema{Type: "integer", Minimum: &minimum, Maximum: &maximum}},
+		)
+		errorStatuses = append(errorStatuses, http.StatusBadRequest)
+	}
+	op.Parameters = append(op.Parameters, o.Query...)
+
+	if o.Body != nil {
+		schema := doc.SchemaOf(o.Body)
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				fiber.MIMEApplicationJSON: {Schema: schema},
+				fiber.MIMEApplicationForm: {Schema: schema},
+			},
+		}
+		errorStatuses = append(errorStatuses, http.StatusBadRequest, http.StatusUnprocessableEntity)
+	}
+
+	if o.Login {
+		op.Security = []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized)
+	}
+	if o.Scope != "" {
+		op.Description = "Access tokens need the `" + o.Scope + "` scope."
+		// Any request may carry a token, and a bad or under-scoped one is rejected.
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	status := o.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := openapi.Response{Description: http.StatusText(status)}
+	if o.Response != nil {
+		data := doc.SchemaOf(o.Response)
+		envelope := &openapi.Schema{Type: "object", Required: []string{"data"}, Properties: map[string]*openapi.Schema{}}
+		if o.List {
+			envelope.Properties["data"] = &openapi.Schema{Type: "array", Items: data}
+			envelope.Properties["pagination"] = paginationSchema
+			envelope.Required = append(envelope.Required, "pagination")
+		} else {
+			envelope.Properties["data"] = data
+		}
+		success.Content = map[string]openapi.MediaType{fiber.MIMEApplicationJSON: {Schema: envelope}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	errorStatuses = append(errorStatuses, http.StatusInternalServerError)
+	for _, code := range errorStatuses {
+		op.Responses[strconv.Itoa(code)] = openapi.Response{
+			Description: http.StatusText(code),
+			Content:     map[string]openapi.MediaType{fiber.MIMEApplicationJSON: {Schema: errorSchema}},
+		}
+	}
+	return op
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestAPISpecMatchesRouter fails when an /api/v1 route is missing from apiOperations, or
+// apiOperations documents a route that is not registered.
+func TestAPISpecMatchesRouter(t *testing.T) {
+	app := fiber.New()
+	RegisterAPIRoutes(app)
+
+	doc, err := APISpec(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Paths) == 0 {
+		t.Fatal("OpenAPI document has no paths")
+	}
+	for path := range doc.Paths {
+		if strings.Contains(path, ":") {
+			t.Errorf("path %q still uses Fiber parameter syntax", path)
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshalling the OpenAPI document: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+	if decoded["openapi"] == nil {
+		t.Error("OpenAPI document has no openapi version")
+	}
+}
+
+func TestAPISpecReportsUndocumentedRoutes(t *testing.T) {
+	app := fiber.New()
+	RegisterAPIRoutes(app)
+	app.Get("/api/v1/undocumented", func(c *fiber.Ctx) error { return nil })
+
+	if _, err := APISpec(app); err == nil || !strings.Contains(err.Error(), "undocumented route GET /api/v1/undocumented") {
+		t.Errorf("APISpec error = %v, want it to report the undocumented route", err)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"Void/internal/db"
+	"Void/internal/models"
+	"Void/internal/policy"
+)
+
+// APIGetShouts returns a page of the global feed, newest first.
+func APIGetShouts(c *fiber.Ctx) error {
+	return apiShoutList(c, withoutMuted(c, db.DB.Preload("User")))
+}
+
+// APIGetTimeline returns a page of the logged-in user's home timeline: their own shouts
+// plus shouts from accounts they follow, newest first.
+func APIGetTimeline(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	return apiShoutList(c, withoutMuted(c, db.DB.Preload("User").
+		Where("user_id = ? OR user_id IN (?)", uid, models.FolloweeIDs(db.DB, uid))))
+}
+
+// APIGetShout returns a single shout.
+func APIGetShout(c *fiber.Ctx) error {
+	shout, err := apiFindShout(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	shouts := []models.Shout{shout}
+	if err := models.LoadEchoCounts(db.DB, shouts); err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusOK, toAPIShout(shouts[0]))
+}
+
+// APICreateShout creates a shout from a {"content": "..."} body and returns it with status 201.
+func APICreateShout(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	var req contentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	shout := models.Shout{
+		Content: req.Content,
+		UserID:  uid,
+	}
+	if err := shout.Create(db.DB); err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusCreated, toAPIShout(shout))
+}
+
+// APIUpdateShout replaces the content of one of the logged-in user's shouts.
+func APIUpdateShout(c *fiber.Ctx) error {
+	shout, err := apiFindShout(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	if !can(c, policy.EditShout, shout) {
+		return apiError(c, fiber.StatusForbidden, "you can only edit your own shouts")
+	}
+
+	var req contentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+	if err := shout.UpdateContent(db.DB, req.Content); err != nil {
+		return apiFail(c, err)
+	}
+
+	shouts := []models.Shout{shout}
+	if err := models.LoadEchoCounts(db.DB, shouts); err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusOK, toAPIShout(shouts[0]))
+}
+
+// APIDeleteShout deletes one of the logged-in user's shouts, or any shout for moderators, and
+// responds with 204 No Content.
+func APIDeleteShout(c *fiber.Ctx) error {
+	shout, err := apiFindShout(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	if !can(c, policy.DeleteShout, shout) {
+		return apiError(c, fiber.StatusForbidden, "you can only delete your own shouts")
+	}
+	if err := shout.Delete(db.DB); err != nil {
+		return apiFail(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// APIGetEchoes returns a page of a shout's echoes, newest first.
+func APIGetEchoes(c *fiber.Ctx) error {
+	shout, err := apiFindShout(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+
+	echoes, page, err := apiFindPage(c, db.DB.Preload("User").Where("shout_id = ?", shout.ID).
+		Where("user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, c.Locals("UserID").(uint))), models.Echo.Cursor)
+	if err != nil {
+		return apiFail(c, err)
+	}
+
+	data := make([]APIEcho, len(echoes))
+	for i, echo := range echoes {
+		data[i] = toAPIEcho(echo)
+	}
+	return apiList(c, data, page)
+}
+
+// APICreateEcho echoes a shout from a {"content": "..."} body and returns the echo with status 201.
+func APICreateEcho(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	shout, err := apiFindShout(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+
+	var req contentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apiError(c, fiber.StatusBadRequest, "invalid request body")
+	}
+
+	echo := models.Echo{
+		Content: req.Content,
+		ShoutID: shout.ID,
+		UserID:  uid,
+	}
+	if err := echo.Create(db.DB); err != nil {
+		return apiFail(c, err)
+	}
+	publishEchoEvent(&echo, shout)
+
+	return apiData(c, fiber.StatusCreated, toAPIEcho(echo))
+}
+
+// APIDeleteEcho deletes one of the logged-in user's echoes, or any echo for moderators, and
+// responds with 204 No Content.
+func APIDeleteEcho(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return apiError(c, fiber.StatusBadRequest, "invalid echo id")
+	}
+
+	var echo models.Echo
+	if err := db.DB.First(&echo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apiError(c, fiber.StatusNotFound, "echo not found")
+		}
+		return apiFail(c, err)
+	}
+	if !can(c, policy.DeleteEcho, echo) {
+		return apiError(c, fiber.StatusForbidden, "you can only delete your own echoes")
+	}
+	if err := echo.Delete(db.DB); err != nil {
+		return apiFail(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// apiShoutList writes one page of query's shouts, with echo counts, as a list response. Shouts the
+// caller may not see, such as those of suspended or blocked users, are left out.
+func apiShoutList(c *fiber.Ctx, query *gorm.DB) error {
+	query = query.Where("shouts.user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, c.Locals("UserID").(uint)))
+	shouts, page, err := apiFindPage(c, query, models.Shout.Cursor)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	if err := models.LoadEchoCounts(db.DB, shouts); err != nil {
+		return apiFail(c, err)
+	}
+
+	data := make([]APIShout, len(shouts))
+	for i, shout := range shouts {
+		data[i] = toAPIShout(shout)
+	}
+	return apiList(c, data, page)
+}
+
+// apiFindShout loads the shout named by the :id route parameter, with its author. Shouts the caller
+// may not see are reported as not found.
+func apiFindShout(c *fiber.Ctx) (models.Shout, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return models.Shout{}, fiber.NewError(fiber.StatusBadRequest, "invalid shout id")
+	}
+
+	var shout models.Shout
+	if err := db.DB.Preload("User").Where("user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, c.Locals("UserID").(uint))).
+		First(&shout, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return shout, fiber.NewError(fiber.StatusNotFound, "shout not found")
+		}
+		return shout, err
+	}
+	return shout, nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// APIGetUser returns a user's public profile with follower and following counts.
+func APIGetUser(c *fiber.Ctx) error {
+	user, err := apiFindUser(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusOK, apiProfile(user))
+}
+
+// APIGetMe returns the logged-in user's profile.
+func APIGetMe(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return apiFail(c, err)
+	}
+	return apiData(c, fiber.StatusOK, apiProfile(user))
+}
+
+// APIGetUserShouts returns a page of a user's shouts, newest first.
+func APIGetUserShouts(c *fiber.Ctx) error {
+	user, err := apiFindUser(c)
+	if err != nil {
+		return apiFail(c, err)
+	}
+	return apiShoutList(c, db.DB.Preload("User").Where("user_id = ?", user.ID))
+}
+
+// apiProfile builds the profile representation of user, including follow counts.
+func apiProfile(user models.User) APIProfile {
+	profile := APIProfile{APIUser: toAPIUser(user)}
+	db.DB.Model(&models.Follow{}).Where("followee_id = ?", user.ID).Count(&profile.FollowerCount)
+	db.DB.Model(&models.Follow{}).Where("follower_id = ?", user.ID).Count(&profile.FollowingCount)
+	return profile
+}
+
+// apiFindUser loads the user named by the :username route parameter.
+func apiFindUser(c *fiber.Ctx) (models.User, error) {
+	var user models.User
+	if err := db.DB.First(&user, "username = ?", c.Params("username")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return user, err
+	}
+	return user, nil
+}
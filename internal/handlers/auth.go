@@ -2,8 +2,16 @@ package handlers
 
 import (
 	"Void/internal/middleware"
+	"Void/pkg/mailer"
+	"Void/pkg/oidc"
 	"Void/pkg/session"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -12,33 +20,76 @@ import (
 	"Void/internal/models"
 )
 
+const (
+	// twoFactorLoginTimeout is how long a half-authenticated login waits for its second factor.
+	twoFactorLoginTimeout = 5 * time.Minute
+
+	// maxTwoFactorAttempts is how many wrong codes a half-authenticated login may submit.
+	maxTwoFactorAttempts = 5
+)
+
 // RegisterAuthRoutes registers all authentication-related routes, including registration, login, and logout handlers.
 func RegisterAuthRoutes(app *fiber.App) {
 	app.Get("/register", middleware.GetUserFromSession, ShowRegister)
 	app.Post("/register", middleware.GetUserFromSession, Register)
 	app.Get("/login", middleware.GetUserFromSession, ShowLogin)
 	app.Post("/login", middleware.GetUserFromSession, Login)
-	app.Get("/logout", middleware.GetUserFromSession, Logout)
+	app.Get("/login/2fa", middleware.GetUserFromSession, ShowLoginTwoFactor)
+	app.Post("/login/2fa", middleware.GetUserFromSession, LoginTwoFactor)
+	app.Post("/logout", middleware.GetUserFromSession, Logout)
+	app.Get("/login/oidc/:provider", middleware.GetUserFromSession, BeginOIDCLogin)
+	app.Get("/login/oidc/:provider/callback", middleware.GetUserFromSession, OIDCCallback)
+	app.Get("/forgot-password", middleware.GetUserFromSession, ShowForgotPassword)
+	app.Post("/forgot-password", middleware.GetUserFromSession, ForgotPassword)
+	app.Get("/reset-password", middleware.GetUserFromSession, ShowResetPassword)
+	app.Post("/reset-password", middleware.GetUserFromSession, ResetPassword)
+	app.Get("/verify-email", middleware.GetUserFromSession, VerifyEmail)
+	app.Post("/verify-email/resend", middleware.GetUserFromSession, middleware.RequireLogin, ResendVerificationEmail)
 }
 
 // ShowRegister renders the registration page using the "register" template and the "layouts/main" layout.
+// The form adapts to the registration mode, and an invite code can be prefilled with ?invite=.
 func ShowRegister(c *fiber.Ctx) error {
+	mode, err := models.GetRegistrationMode(db.DB)
+	if err != nil {
+		return c.Status(500).SendString("Error loading registration settings")
+	}
 
 	return c.Render("register", fiber.Map{
 		"UserID": nil,
+		"Mode":   mode,
+		"Invite": c.Query("invite"),
 	}, "layouts/main")
 }
 
 // Register handles user registration by creating a new user with hashed password and saving it to the database.
-// It validates required input fields (username, email, password) and redirects to the login page upon success.
-// Returns an error or appropriate HTTP response in case of failure or missing fields.
+// Missing fields, taken usernames or emails, invalid invite codes and registrations the mode does not allow
+// re-render the form with an error. On success a verification email is sent and the user is redirected to the
+// login page; they can log in once approved, if approval is required, but cannot post until verified.
 func Register(c *fiber.Ctx) error {
 	username := c.FormValue("username")
 	email := c.FormValue("email")
 	password := c.FormValue("password")
+	invite := c.FormValue("invite")
+
+	mode, err := models.GetRegistrationMode(db.DB)
+	if err != nil {
+		return c.Status(500).SendString("Error loading registration settings")
+	}
 
-	if username == "" || email == "" || password == "" {
-		return c.SendString("All fields are required")
+	renderError := func(message string) error {
+		return c.Status(fiber.StatusUnprocessableEntity).Render("register", fiber.Map{
+			"UserID":   nil,
+			"Mode":     mode,
+			"Error":    message,
+			"Username": username,
+			"Email":    email,
+			"Invite":   invite,
+		}, "layouts/main")
+	}
+
+	if password == "" {
+		return renderError("all fields are required")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -51,23 +102,57 @@ func Register(c *fiber.Ctx) error {
 		Email:    email,
 		Password: string(hashedPassword),
 	}
-	db.DB.Create(&user)
+	if err := models.RegisterUser(db.DB, &user, invite); err != nil {
+		if models.IsValidationError(err) {
+			return renderError(err.Error())
+		}
+		log.Printf("Error registering user: %v", err)
+		return c.Status(500).SendString("Error creating account")
+	}
 
-	return c.Redirect("/login")
+	if err := user.MarkVerificationSent(db.DB); err != nil {
+		log.Printf("Error recording verification email for user %d: %v", user.ID, err)
+	}
+	go sendVerificationEmail(user, absoluteURL("/verify-email?token="+url.QueryEscape(models.NewEmailVerificationToken(user))))
+
+	if user.ApprovalPending {
+		return c.Redirect("/login?pending=1")
+	}
+	return c.Redirect("/login?registered=1")
 }
 
 // ShowLogin renders the login page using the "login" template and the "layouts/main" layout.
 func ShowLogin(c *fiber.Ctx) error {
 	log.Println("Rendering login template")
-	return c.Render("login", fiber.Map{
+	data := fiber.Map{
 		"UserID": nil,
-	}, "layouts/main")
+	}
+	if c.Query("reset") != "" {
+		data["Message"] = "Your password has been reset. Please log in with your new password."
+	}
+	if c.Query("registered") != "" {
+		data["Message"] = "Welcome to Void! We've emailed you a link to verify your address. Please log in."
+	}
+	if c.Query("pending") != "" {
+		data["Message"] = pendingApprovalMessage + " We've emailed you a link to verify your address in the meantime."
+	}
+	return renderLogin(c, data)
+}
+
+// renderLogin renders the login form with data, such as an error message, and a sign-in button
+// for each configured identity provider.
+func renderLogin(c *fiber.Ctx, data fiber.Map) error {
+	data["UserID"] = nil
+	data["Providers"] = oidc.Providers
+	return c.Render("login", data, "layouts/main")
 }
 
 // Login handles user authentication by verifying their email and password and establishes a session upon success.
 // It fetches the user from the database using the provided email and validates the password using bcrypt.
 // On successful authentication, the user's ID is saved in the session, and the user is redirected to the homepage.
-// Returns an error response in case of missing user, invalid credentials, or session handling issues.
+// Every attempt is recorded; repeated failures for an email or from an IP address lock logins out with
+// exponential backoff. Unknown emails and wrong passwords get the same response, so the form cannot be
+// used to discover which addresses are registered.
 func Login(c *fiber.Ctx) error {
 	log.Println("Login handler reached")
 	email := c.FormValue("email")
@@ -75,26 +160,377 @@ func Login(c *fiber.Ctx) error {
 
 	var user models.User
 	result := db.DB.Where("email = ?", email).First(&user)
+
+	if locked, err := checkLoginLockout(c, email, user.ID); locked || err != nil {
+		return err
+	}
 	if result.Error != nil {
-		return c.SendString("User not found")
+		// Compare against a dummy hash so unknown emails take as long as wrong passwords.
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return loginFailed(c, email, 0, models.LoginFailedPassword)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return c.SendString("Invalid credentials")
+		return loginFailed(c, email, user.ID, models.LoginFailedPassword)
+	}
+
+	return passedFirstFactor(c, user)
+}
+
+// pendingApprovalMessage is shown to users who try to log in before an admin approves their account.
+const pendingApprovalMessage = "Thanks for registering! An admin needs to approve your account before you can log in."
+
+// passedFirstFactor continues a login once the user has proven their password or identity provider
+// account. Accounts waiting for approval, suspended or banned are turned away. Accounts with two-factor
+// authentication stay half-authenticated until the code is checked.
+func passedFirstFactor(c *fiber.Ctx, user models.User) error {
+	if user.ApprovalPending {
+		return renderLogin(c.Status(fiber.StatusForbidden), fiber.Map{"Message": pendingApprovalMessage})
+	}
+	if user.Banned() {
+		return renderLogin(c.Status(fiber.StatusForbidden), fiber.Map{"Error": "This account has been banned."})
+	}
+	if user.Suspended() {
+		return renderLogin(c.Status(fiber.StatusForbidden), fiber.Map{
+			"Error": "This account is suspended until " + user.SuspendedUntil.Format("January 2, 2006 15:04 MST") + ".",
+		})
+	}
+	if user.TOTPEnabled {
+		if err := session.SetPendingUserID(c, user.ID); err != nil {
+			return c.Status(500).SendString("Failed to save session")
+		}
+		return c.Redirect("/login/2fa")
 	}
 
+	return completeLogin(c, user)
+}
+
+// completeLogin records a successful login, saves the user in a new session and lists the
+// session on the user's active sessions page.
+func completeLogin(c *fiber.Ctx, user models.User) error {
+	recordLoginAttempt(c, user.Email, user.ID, true, "")
+
 	// Set the user ID in the session using our session package helper.
-	if err := session.SetUserID(c, user.ID); err != nil {
+	sessionID, err := session.SetUserID(c, user.ID, user.SessionVersion)
+	if err != nil {
+		return c.Status(500).SendString("Failed to save session")
+	}
+
+	now := time.Now()
+	record := models.UserSession{
+		SessionID:  sessionID,
+		UserID:     user.ID,
+		IP:         c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := db.DB.Create(&record).Error; err != nil {
+		session.Revoke(sessionID)
 		return c.Status(500).SendString("Failed to save session")
 	}
 
 	return c.Redirect("/")
 }
 
+// dummyPasswordHash is compared against when a login names an unknown email.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// loginFailedMessage is shown for every failed password check, whatever the reason.
+const loginFailedMessage = "Invalid email or password"
+
+// loginFailed records a failed password check and re-renders the login form.
+func loginFailed(c *fiber.Ctx, email string, userID uint, reason string) error {
+	recordLoginAttempt(c, email, userID, false, reason)
+	return renderLogin(c.Status(fiber.StatusUnauthorized), fiber.Map{
+		"Error": loginFailedMessage,
+		"Email": email,
+	})
+}
+
+// checkLoginLockout reports whether logins for email from the client's IP address are locked out,
+// in which case it has already recorded the refused attempt and rendered the login form.
+func checkLoginLockout(c *fiber.Ctx, email string, userID uint) (bool, error) {
+	until, err := models.LoginLockedUntil(db.DB, email, c.IP())
+	if err != nil {
+		log.Printf("Error checking login lockout: %v", err)
+		return true, c.Status(500).SendString("Error logging in")
+	}
+	if until.IsZero() {
+		return false, nil
+	}
+
+	recordLoginAttempt(c, email, userID, false, models.LoginFailedLockedOut)
+	wait := time.Until(until).Round(time.Second)
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())))
+	return true, renderLogin(c.Status(fiber.StatusTooManyRequests), fiber.Map{
+		"Error": fmt.Sprintf("Too many failed login attempts. Please try again in %s.", wait),
+		"Email": email,
+	})
+}
+
+// recordLoginAttempt stores a login attempt from the current request, logging any failure.
+func recordLoginAttempt(c *fiber.Ctx, email string, userID uint, success bool, reason string) {
+	err := models.RecordLoginAttempt(db.DB, models.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Success:   success,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Printf("Error recording login attempt: %v", err)
+	}
+}
+
+// ShowLoginTwoFactor renders the second login step for a half-authenticated session.
+func ShowLoginTwoFactor(c *fiber.Ctx) error {
+	if _, err := session.GetPendingUserID(c, twoFactorLoginTimeout); err != nil {
+		return c.Redirect("/login")
+	}
+	return c.Render("login_2fa", fiber.Map{
+		"UserID": nil,
+	}, "layouts/main")
+}
+
+// LoginTwoFactor completes a half-authenticated login with a TOTP or recovery code. After
+// maxTwoFactorAttempts wrong codes the pending login is discarded and the password must be entered again.
+func LoginTwoFactor(c *fiber.Ctx) error {
+	uid, err := session.GetPendingUserID(c, twoFactorLoginTimeout)
+	if err != nil {
+		return c.Redirect("/login")
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return c.Redirect("/login")
+	}
+	if locked, err := checkLoginLockout(c, user.Email, user.ID); locked || err != nil {
+		return err
+	}
+
+	if err := user.VerifySecondFactor(db.DB, c.FormValue("code")); err != nil {
+		if !models.IsValidationError(err) {
+			log.Printf("Error verifying second factor for user %d: %v", uid, err)
+			return c.Status(500).SendString("Error verifying code")
+		}
+		recordLoginAttempt(c, user.Email, user.ID, false, models.LoginFailedTwoFactor)
+		attempts, _ := session.AddPendingAttempt(c)
+		if attempts >= maxTwoFactorAttempts {
+			session.DestroySession(c)
+			return c.Redirect("/login")
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).Render("login_2fa", fiber.Map{
+			"UserID": nil,
+			"Error":  err.Error(),
+		}, "layouts/main")
+	}
+
+	return completeLogin(c, user)
+}
+
 // Logout terminates the user's session and redirects them to the login page. Returns an error on failure.
 func Logout(c *fiber.Ctx) error {
+	if sessionID, err := session.ID(c); err == nil {
+		if err := models.DeleteUserSession(db.DB, sessionID); err != nil {
+			log.Printf("Error deleting session record: %v", err)
+		}
+	}
 	if err := session.DestroySession(c); err != nil {
 		return c.Status(500).SendString("Failed to Log out.")
 	}
 	return c.Redirect("/login")
 }
+
+// ShowForgotPassword renders the form for requesting a password reset link.
+func ShowForgotPassword(c *fiber.Ctx) error {
+	return c.Render("forgot_password", fiber.Map{
+		"UserID": nil,
+	}, "layouts/main")
+}
+
+// ForgotPassword emails a single-use password reset link to the account with the submitted email.
+// The response is the same whether or not such an account exists, so the form cannot be used to
+// discover which addresses are registered.
+func ForgotPassword(c *fiber.Ctx) error {
+	email := strings.TrimSpace(c.FormValue("email"))
+
+	var user models.User
+	if email != "" && db.DB.Where("email = ?", email).First(&user).Error == nil {
+		token, err := models.NewPasswordResetToken(db.DB, user.ID)
+		if err != nil {
+			log.Printf("Error creating password reset token for user %d: %v", user.ID, err)
+			return c.Status(500).SendString("Error requesting password reset")
+		}
+		link := absoluteURL("/reset-password?token=" + url.QueryEscape(token))
+		go sendPasswordResetEmail(user, link)
+	}
+
+	return c.Render("forgot_password", fiber.Map{
+		"UserID":  nil,
+		"Message": "If an account exists for that email, we've sent a link to reset its password.",
+	}, "layouts/main")
+}
+
+// sendPasswordResetEmail emails link to user, logging any failure.
+func sendPasswordResetEmail(user models.User, link string) {
+	err := mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Void password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password for your Void account. If it was you, open this link "+
+			"within %d minutes to choose a new password:\n\n%s\n\n"+
+			"If it wasn't, you can ignore this email; your password has not changed.\n",
+			user.Username, int(models.PasswordResetTTL.Minutes()), link),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset email to user %d: %v", user.ID, err)
+	}
+}
+
+// ShowResetPassword renders the form for choosing a new password, if the link's token is still valid.
+func ShowResetPassword(c *fiber.Ctx) error {
+	token := c.Query("token")
+	data := fiber.Map{
+		"UserID": nil,
+		"Token":  token,
+	}
+	if _, err := models.FindPasswordResetToken(db.DB, token); err != nil {
+		data["Error"] = models.ErrInvalidResetToken.Error()
+	}
+	return c.Render("reset_password", data, "layouts/main")
+}
+
+// ResetPassword sets a new password using a reset token. The token is used up and every existing
+// session for the account is signed out, after which the user is sent to the login page.
+func ResetPassword(c *fiber.Ctx) error {
+	token := c.FormValue("token")
+	password := c.FormValue("password")
+
+	var err error
+	if password != c.FormValue("password_confirmation") {
+		err = &models.ValidationError{Message: "passwords do not match"}
+	} else {
+		err = models.ResetPassword(db.DB, token, password)
+	}
+	if err != nil {
+		if !models.IsValidationError(err) && !errors.Is(err, models.ErrInvalidResetToken) {
+			log.Printf("Error resetting password: %v", err)
+			return c.Status(500).SendString("Error resetting password")
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).Render("reset_password", fiber.Map{
+			"UserID": nil,
+			"Token":  token,
+			"Error":  err.Error(),
+		}, "layouts/main")
+	}
+
+	// The reset bumped the session version, so this browser's session, if any, is no longer valid.
+	session.DestroySession(c)
+	return c.Redirect("/login?reset=1")
+}
+
+// VerifyEmail confirms a user's email address from the link in their verification email. Without a
+// token it shows a logged-in, unverified user how to get a new link.
+func VerifyEmail(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	data := fiber.Map{
+		"UserID": uid,
+	}
+
+	if token := c.Query("token"); token != "" {
+		user, err := models.VerifyEmail(db.DB, token)
+		if err != nil {
+			if !errors.Is(err, models.ErrInvalidVerificationToken) {
+				log.Printf("Error verifying email: %v", err)
+				return c.Status(500).SendString("Error verifying email")
+			}
+			data["Error"] = err.Error()
+		} else {
+			data["Verified"] = true
+			data["Email"] = user.Email
+		}
+		return c.Render("verify_email", data, "layouts/main")
+	}
+
+	if uid == 0 {
+		return c.Redirect("/login")
+	}
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).SendString("User not found")
+	}
+	data["Verified"] = user.Verified
+	data["Email"] = user.Email
+	if c.Query("sent") != "" {
+		data["Message"] = "We've sent a new verification link to " + user.Email + "."
+	}
+	return c.Render("verify_email", data, "layouts/main")
+}
+
+// ResendVerificationEmail emails the logged-in user a new verification link, at most once per
+// models.VerificationResendInterval.
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).SendString("User not found")
+	}
+	if user.Verified {
+		return c.Redirect("/verify-email")
+	}
+
+	if err := user.MarkVerificationSent(db.DB); err != nil {
+		if !models.IsValidationError(err) {
+			log.Printf("Error recording verification email for user %d: %v", user.ID, err)
+			return c.Status(500).SendString("Error sending verification email")
+		}
+		return c.Status(fiber.StatusTooManyRequests).Render("verify_email", fiber.Map{
+			"UserID": uid,
+			"Email":  user.Email,
+			"Error":  err.Error(),
+		}, "layouts/main")
+	}
+	go sendVerificationEmail(user, absoluteURL("/verify-email?token="+url.QueryEscape(models.NewEmailVerificationToken(user))))
+
+	return c.Redirect("/verify-email?sent=1")
+}
+
+// sendVerificationEmail emails link to user, logging any failure.
+func sendVerificationEmail(user models.User, link string) {
+	err := mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Void email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Welcome to Void! Open this link within %d hours to confirm your email address and start posting:\n\n%s\n\n"+
+			"If you didn't create a Void account, you can ignore this email.\n",
+			user.Username, int(models.EmailVerificationTTL.Hours()), link),
+	})
+	if err != nil {
+		log.Printf("Error sending verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// baseURL is the public URL of the site, without a trailing slash. Links in emails and the OIDC
+// redirect URI are built from it, never from request headers, which a client controls.
+var baseURL string
+
+// SetBaseURL sets the public URL every absolute link is built from, such as
+// "https://void.example". It must be an absolute http or https URL.
+func SetBaseURL(raw string) error {
+	if raw == "" {
+		return errors.New("BASE_URL must be set to the site's public URL")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("BASE_URL %q must be an absolute http or https URL", raw)
+	}
+	baseURL = strings.TrimSuffix(raw, "/")
+	return nil
+}
+
+// absoluteURL returns an absolute link to path, based on the URL passed to SetBaseURL.
+func absoluteURL(path string) string {
+	return baseURL + path
+}
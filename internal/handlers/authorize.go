@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/policy"
+)
+
+// can reports whether the logged-in user may perform action on resource; see policy.Can.
+// Anonymous requests, and users that cannot be loaded, may do nothing.
+func can(c *fiber.Ctx, action policy.Action, resource interface{}) bool {
+	if c.Locals("UserID").(uint) == 0 {
+		return false
+	}
+	actor, err := currentUser(c)
+	if err != nil {
+		return false
+	}
+	return policy.Can(actor, action, resource)
+}
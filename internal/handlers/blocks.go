@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// blocksPage is the management page listing the logged-in user's blocks and mutes.
+const blocksPage = "/profile/blocks"
+
+// ShowBlocks renders the page listing the users the logged-in user has blocked or muted.
+func ShowBlocks(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	blocks, err := models.UserBlocks(db.DB, uid)
+	if err != nil {
+		log.Printf("Error fetching blocks for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error loading blocked users")
+	}
+	mutes, err := models.UserMutes(db.DB, uid)
+	if err != nil {
+		log.Printf("Error fetching mutes for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error loading muted users")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("blocks", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Blocks":            blocks,
+		"Mutes":             mutes,
+	}, "layouts/main")
+}
+
+// BlockUser blocks the user identified by the username parameter for the logged-in user.
+func BlockUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	user, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+	if err := models.BlockUser(db.DB, uid, user.ID); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+	return redirectAfterBlock(c, user)
+}
+
+// UnblockUser removes the logged-in user's block of the user identified by the username parameter.
+func UnblockUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	user, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+	if err := models.UnblockUser(db.DB, uid, user.ID); err != nil {
+		return c.Status(500).SendString("Failed to unblock user")
+	}
+	return redirectAfterBlock(c, user)
+}
+
+// MuteUser mutes the user identified by the username parameter for the logged-in user, for the
+// number of days in the "days" form field, or until unmuted if it is empty.
+func MuteUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	user, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+
+	var expiresAt *time.Time
+	if days := c.FormValue("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return c.Status(400).SendString("Invalid mute duration")
+		}
+		until := time.Now().AddDate(0, 0, n)
+		expiresAt = &until
+	}
+	if err := models.MuteUser(db.DB, uid, user.ID, expiresAt); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+	return redirectAfterBlock(c, user)
+}
+
+// UnmuteUser removes the logged-in user's mute of the user identified by the username parameter.
+func UnmuteUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	user, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+	if err := models.UnmuteUser(db.DB, uid, user.ID); err != nil {
+		return c.Status(500).SendString("Failed to unmute user")
+	}
+	return redirectAfterBlock(c, user)
+}
+
+// findUserByUsername loads the user named by the :username route parameter.
+func findUserByUsername(c *fiber.Ctx) (models.User, error) {
+	var user models.User
+	if err := db.DB.First(&user, "username = ?", c.Params("username")).Error; err != nil {
+		return user, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	return user, nil
+}
+
+// redirectAfterBlock sends the user back to the blocks page if the form came from there, and to
+// the other user's profile otherwise.
+func redirectAfterBlock(c *fiber.Ctx, user models.User) error {
+	if c.FormValue("next") == blocksPage {
+		return c.Redirect(blocksPage)
+	}
+	return c.Redirect("/users/" + user.Username)
+}
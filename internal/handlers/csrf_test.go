@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// loggedInClient creates a user and returns a client signed in as them, with a CSRF token for
+// the signed-in session.
+func loggedInClient(t *testing.T, app *fiber.App) (*testClient, string) {
+	t.Helper()
+	createUser(t, "alice", "alice@example.com")
+	client := newTestClient(t, app)
+	client.login("alice@example.com")
+	return client, client.csrfToken("/")
+}
+
+// forgedTokens are the CSRF tokens a cross-site request could send: none, or a guess.
+var forgedTokens = map[string]string{"missing": "", "wrong": "not-the-token"}
+
+func TestCSRFProtectsForms(t *testing.T) {
+	for _, route := range []struct {
+		path     string
+		form     url.Values
+		location string
+	}{
+		{"/shout", url.Values{"content": {"hello"}}, "/"},
+		{"/logout", url.Values{}, "/login"},
+	} {
+		t.Run(route.path, func(t *testing.T) {
+			app := newTestApp(t)
+			client, token := loggedInClient(t, app)
+
+			for name, forged := range forgedTokens {
+				form := url.Values{}
+				for key, values := range route.form {
+					form[key] = values
+				}
+				if forged != "" {
+					form.Set("_csrf", forged)
+				}
+				if resp := client.postForm(route.path, form); resp.StatusCode != fiber.StatusForbidden {
+					t.Errorf("%s token: status %d, want 403", name, resp.StatusCode)
+				}
+			}
+			// The forged requests changed nothing: the user is still signed in.
+			if resp := client.get("/"); resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("GET / after forged requests: status %d, want 200", resp.StatusCode)
+			}
+
+			form := url.Values{"_csrf": {token}}
+			for key, values := range route.form {
+				form[key] = values
+			}
+			resp := client.postForm(route.path, form)
+			if resp.StatusCode != fiber.StatusFound || resp.Header.Get(fiber.HeaderLocation) != route.location {
+				t.Errorf("valid token: status %d, location %q; want a redirect to %s", resp.StatusCode, resp.Header.Get(fiber.HeaderLocation), route.location)
+			}
+		})
+	}
+}
+
+func TestCSRFAcceptsHeader(t *testing.T) {
+	app := newTestApp(t)
+	client, token := loggedInClient(t, app)
+
+	for name, header := range map[string]string{"wrong": "not-the-token", "valid": token} {
+		req := httptest.NewRequest(fiber.MethodPost, "/shout", strings.NewReader("content=hello"))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		req.Header.Set("X-Csrf-Token", header)
+		resp := client.do(req)
+
+		want := fiber.StatusFound
+		if name == "wrong" {
+			want = fiber.StatusForbidden
+		}
+		if resp.StatusCode != want {
+			t.Errorf("%s X-Csrf-Token header: status %d, want %d", name, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestCSRFProtectsSessionAPIWrites(t *testing.T) {
+	app := newTestApp(t)
+	client, token := loggedInClient(t, app)
+
+	post := func(csrfToken string) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/shouts", strings.NewReader(`{"content":"hello"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if csrfToken != "" {
+			req.Header.Set("X-Csrf-Token", csrfToken)
+		}
+		return client.do(req).StatusCode
+	}
+	for name, forged := range forgedTokens {
+		if status := post(forged); status != fiber.StatusForbidden {
+			t.Errorf("%s token: status %d, want 403", name, status)
+		}
+	}
+	if status := post(token); status != fiber.StatusCreated {
+		t.Errorf("valid token: status %d, want 201", status)
+	}
+}
+
+func TestCSRFIgnoresNonBearerAuthorization(t *testing.T) {
+	app := newTestApp(t)
+	client, _ := loggedInClient(t, app)
+
+	// Browsers resend cached Basic credentials cross-site, e.g. behind an HTTP-auth proxy, so they
+	// must not stand in for the CSRF token on routes authenticated by the session cookie.
+	for _, header := range []string{"Basic dXNlcjpwYXNz", "Bearer void_notatoken"} {
+		req := httptest.NewRequest(fiber.MethodPost, "/shout", strings.NewReader("content=hello"))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		req.Header.Set(fiber.HeaderAuthorization, header)
+		if resp := client.do(req); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("POST /shout with Authorization %q and no token: status %d, want 403", header, resp.StatusCode)
+		}
+	}
+}
+
+func TestCSRFSkipsBearerAPIRequests(t *testing.T) {
+	app := newTestApp(t)
+	user := createUser(t, "alice", "alice@example.com")
+	token, plaintext, err := models.NewAccessToken(user.ID, "script", []string{models.ScopeWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Create(token).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/shouts", strings.NewReader(`{"content":"hello"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+plaintext)
+	if resp := newTestClient(t, app).do(req); resp.StatusCode != fiber.StatusCreated {
+		t.Errorf("API write with a Bearer token and no CSRF token: status %d, want 201", resp.StatusCode)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/policy"
+)
+
+// RegisterInviteRoutes registers the routes for creating and revoking invite codes.
+func RegisterInviteRoutes(app *fiber.App) {
+	app.Get("/invites", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireVerified, ShowInvites)
+	app.Post("/invites", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireVerified, CreateInvite)
+	app.Post("/invites/:id/revoke", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireVerified, RevokeInvite)
+}
+
+// inviteView is an invite with its shareable registration link.
+type inviteView struct {
+	models.Invite
+	Link string
+}
+
+// ShowInvites lists the logged-in user's invites.
+func ShowInvites(c *fiber.Ctx) error {
+	return renderInvites(c, fiber.Map{})
+}
+
+// renderInvites renders the invites page merged with extra data such as an error.
+func renderInvites(c *fiber.Ctx, data fiber.Map) error {
+	uid := c.Locals("UserID").(uint)
+
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return c.SendString("User not found")
+	}
+	invites, err := models.UserInvites(db.DB, uid)
+	if err != nil {
+		log.Printf("Error fetching invites for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error loading invites")
+	}
+	views := make([]inviteView, len(invites))
+	for i, invite := range invites {
+		views[i] = inviteView{Invite: invite, Link: absoluteURL("/register?invite=" + url.QueryEscape(invite.Code))}
+	}
+	mode, err := models.GetRegistrationMode(db.DB)
+	if err != nil {
+		return c.Status(500).SendString("Error loading registration settings")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	data["UserID"] = uid
+	data["NotificationCount"] = count
+	data["Invites"] = views
+	data["Unrestricted"] = policy.Can(user, policy.CreateUnlimitedInvites, nil)
+	data["Mode"] = mode
+	data["MaxUses"] = models.MaxUserInviteUses
+	data["MaxDays"] = int(models.MaxUserInviteTTL.Hours() / 24)
+	return c.Render("invites", data, "layouts/main")
+}
+
+// CreateInvite creates an invite from the uses and days fields. Users allowed to create unlimited
+// invites may leave either at zero for no limit.
+func CreateInvite(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	var user models.User
+	if err := db.DB.First(&user, uid).Error; err != nil {
+		return c.SendString("User not found")
+	}
+	uses, err := strconv.Atoi(c.FormValue("uses"))
+	if err != nil {
+		return renderInvites(c.Status(fiber.StatusUnprocessableEntity), fiber.Map{"Error": "enter how many times the invite can be used"})
+	}
+	days, err := strconv.Atoi(c.FormValue("days"))
+	if err != nil {
+		return renderInvites(c.Status(fiber.StatusUnprocessableEntity), fiber.Map{"Error": "enter how many days the invite lasts"})
+	}
+
+	unrestricted := policy.Can(user, policy.CreateUnlimitedInvites, nil)
+	if _, err := models.NewInvite(db.DB, user, unrestricted, uses, time.Duration(days)*24*time.Hour); err != nil {
+		if models.IsValidationError(err) {
+			return renderInvites(c.Status(fiber.StatusUnprocessableEntity), fiber.Map{"Error": err.Error()})
+		}
+		log.Printf("Error creating invite for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error creating invite")
+	}
+	return c.Redirect("/invites")
+}
+
+// RevokeInvite stops one of the logged-in user's invites from being used.
+func RevokeInvite(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	inviteID, err := c.ParamsInt("id")
+	if err != nil || inviteID <= 0 {
+		return c.Status(400).SendString("Invalid invite")
+	}
+	if err := models.RevokeInvite(db.DB, uid, uint(inviteID)); err != nil {
+		return c.Status(404).SendString("Invite not found")
+	}
+	return c.Redirect("/invites")
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestLoginFailuresLookAlike checks that a login for an unknown email cannot be told apart from
+// a wrong password, so the form does not reveal which emails have accounts.
+func TestLoginFailuresLookAlike(t *testing.T) {
+	app := newTestApp(t)
+	createUser(t, "alice", "alice@example.com")
+	client := newTestClient(t, app)
+
+	attempt := func(email, password string) (int, string) {
+		t.Helper()
+		resp := client.postForm("/login", url.Values{
+			"email": {email}, "password": {password}, "_csrf": {client.csrfToken("/login")},
+		})
+		// The form echoes the email back, and the CSRF token is per session; neither tells the cases apart.
+		body := strings.ReplaceAll(readBody(t, resp), email, "EMAIL")
+		return resp.StatusCode, csrfFieldPattern.ReplaceAllString(body, "")
+	}
+
+	unknownStatus, unknownBody := attempt("nobody@example.com", testPassword)
+	wrongStatus, wrongBody := attempt("alice@example.com", "not the password")
+
+	if unknownStatus != fiber.StatusUnauthorized || wrongStatus != fiber.StatusUnauthorized {
+		t.Errorf("statuses: unknown email %d, wrong password %d; want 401 for both", unknownStatus, wrongStatus)
+	}
+	if !strings.Contains(unknownBody, loginFailedMessage) {
+		t.Errorf("unknown email: response does not say %q", loginFailedMessage)
+	}
+	if unknownBody != wrongBody {
+		t.Error("the responses for an unknown email and a wrong password differ")
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	htmlengine "github.com/gofiber/template/html/v2"
+	"golang.org/x/crypto/bcrypt"
+
+	"Void/internal/db"
+	"Void/internal/events"
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/policy"
+	"Void/internal/views"
+	"Void/pkg/mailer"
+	"Void/pkg/session"
+	"Void/pkg/signer"
+)
+
+// testBaseURL is the public URL the test app builds absolute links from.
+const testBaseURL = "https://void.test"
+
+// testPassword is the password of every user created by createUser.
+const testPassword = "correct horse"
+
+func TestMain(m *testing.M) {
+	session.InitStore()
+	signer.Init("test-secret")
+	mailer.Default = discardMailer{}
+	// Events are dropped rather than sent to RabbitMQ.
+	events.Transport = func([]byte) error { return nil }
+	if err := SetBaseURL(testBaseURL); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// discardMailer drops every message.
+type discardMailer struct{}
+
+func (discardMailer) Send(mailer.Message) error { return nil }
+
+// newTestApp points db.DB at a fresh in-memory database and returns an app with the same
+// middleware and routes as cmd/server, minus the RabbitMQ consumer.
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	conn, err := db.Open("file:" + url.PathEscape(t.Name()) + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	db.DB = conn
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	engine := htmlengine.New("../../web/templates", ".html")
+	engine.AddFunc("formatDate", func(t time.Time) string { return t.Format("Jan 2, 2006 at 3:04pm") })
+	engine.AddFunc("renderContent", views.RenderContent)
+	engine.AddFunc("csrfField", views.CSRFField)
+	engine.AddFunc("can", policy.Can)
+
+	app := fiber.New(fiber.Config{Views: engine, PassLocalsToViews: true})
+	app.Use(middleware.CSRF())
+	RegisterAuthRoutes(app)
+	RegisterUserRoutes(app)
+	RegisterInviteRoutes(app)
+	RegisterAdminRoutes(app)
+	RegisterReportRoutes(app)
+	RegisterTagRoutes(app)
+	RegisterSearchRoutes(app)
+	RegisterAPIRoutes(app)
+	RegisterVoidRoutes(app)
+	RegisterNotificationRoutes(app)
+	return app
+}
+
+// createUser stores a verified user with testPassword.
+func createUser(t *testing.T, username, email string) models.User {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: username, Email: email, Password: string(hashed), Verified: true}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("creating user %s: %v", username, err)
+	}
+	return user
+}
+
+// testClient sends requests to an app like a browser, keeping its cookies between requests.
+type testClient struct {
+	t       *testing.T
+	app     *fiber.App
+	cookies map[string]string
+}
+
+func newTestClient(t *testing.T, app *fiber.App) *testClient {
+	return &testClient{t: t, app: app, cookies: map[string]string{}}
+}
+
+// do sends req with the client's cookies and stores any cookies the response sets.
+func (c *testClient) do(req *http.Request) *http.Response {
+	c.t.Helper()
+	for name, value := range c.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	resp, err := c.app.Test(req, -1)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value == "" || cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return resp
+}
+
+func (c *testClient) get(path string) *http.Response {
+	c.t.Helper()
+	return c.do(httptest.NewRequest(fiber.MethodGet, path, nil))
+}
+
+func (c *testClient) postForm(path string, form url.Values) *http.Response {
+	c.t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	return c.do(req)
+}
+
+// csrfFieldPattern matches the hidden field rendered by the csrfField template helper.
+var csrfFieldPattern = regexp.MustCompile(`name="_csrf" value="([^"]+)"`)
+
+// csrfToken loads the page at path and returns the CSRF token from its first form.
+func (c *testClient) csrfToken(path string) string {
+	c.t.Helper()
+	resp := c.get(path)
+	m := csrfFieldPattern.FindStringSubmatch(readBody(c.t, resp))
+	if m == nil {
+		c.t.Fatalf("no CSRF token on %s (status %d)", path, resp.StatusCode)
+	}
+	return html.UnescapeString(m[1])
+}
+
+// login signs the client in with the email and testPassword.
+func (c *testClient) login(email string) {
+	c.t.Helper()
+	resp := c.postForm("/login", url.Values{
+		"email": {email}, "password": {testPassword}, "_csrf": {c.csrfToken("/login")},
+	})
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get(fiber.HeaderLocation) != "/" {
+		c.t.Fatalf("logging in as %s: status %d, location %q", email, resp.StatusCode, resp.Header.Get(fiber.HeaderLocation))
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	var b strings.Builder
+	if _, err := io.Copy(&b, resp.Body); err != nil {
+		t.Fatalf("reading response body: %v", err)
+	}
+	return b.String()
+}
@@ -1,20 +1,31 @@
 package handlers
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"Void/internal/db"
 	"Void/internal/middleware"
 	"Void/internal/models"
+	"Void/internal/pagination"
+	"Void/internal/policy"
+	"Void/internal/services/notifications"
 )
 
+// streamHeartbeat is how often an idle notification stream sends a keep-alive comment.
+const streamHeartbeat = 15 * time.Second
+
 func RegisterNotificationRoutes(app *fiber.App) {
 	// Group this route so that only logged-in users can access it.
 	authGroup := app.Group("/", middleware.GetUserFromSession, middleware.RequireLogin)
 	authGroup.Get("/notifications", middleware.GetUserFromSession, GetNotifications)
-	authGroup.Get("/notifications/:id/read", middleware.GetUserFromSession, MarkNotificationAsRead)
+	authGroup.Get("/notifications/stream", middleware.GetUserFromSession, StreamNotifications)
+	authGroup.Post("/notifications/:id/read", middleware.GetUserFromSession, MarkNotificationAsRead)
 
 }
 
@@ -23,12 +34,16 @@ func GetNotifications(c *fiber.Ctx) error {
 	// Get session and the user ID
 	uid := c.Locals("UserID").(uint)
 	if uid != 0 {
-		// Query the notifications for this user, ordered by newest first.
-		var notifications []models.Notification
-		if err := db.DB.
-			Where("user_id = ?", uid).
-			Order("created_at desc").
-			Find(&notifications).Error; err != nil {
+		req, err := pagination.FromRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+
+		// Query a page of unread notifications for this user, ordered by newest first.
+		notifications, page, err := pagination.Find(db.DB.
+			Where("user_id = ? AND read = ?", uid, false),
+			req, models.Notification.Cursor)
+		if err != nil {
 			log.Printf("Error fetching notifications: %v", err)
 			return c.Status(500).SendString("Error fetching notifications")
 		}
@@ -39,6 +54,7 @@ func GetNotifications(c *fiber.Ctx) error {
 		// Render the notifications view.
 		return c.Render("notifications", fiber.Map{
 			"Notifications":     notifications,
+			"Page":              page,
 			"UserID":            uid,
 			"NotificationCount": count,
 		}, "layouts/main")
@@ -51,9 +67,6 @@ func GetNotifications(c *fiber.Ctx) error {
 // It validates the session, ensures the notification exists, and belongs to the user before updating it.
 // Redirects the user to the notifications page upon success. Returns an error or HTTP status code on failure.
 func MarkNotificationAsRead(c *fiber.Ctx) error {
-	// Retrieve the session and user id.
-	uid := c.Locals("UserID").(uint)
-
 	// Get the notification id from the URL.
 	notifID := c.Params("id")
 	var notif models.Notification
@@ -62,7 +75,7 @@ func MarkNotificationAsRead(c *fiber.Ctx) error {
 	}
 
 	// Ensure the notification belongs to the current user.
-	if notif.UserID != uid {
+	if !can(c, policy.ReadNotification, notif) {
 		return c.Status(fiber.StatusForbidden).SendString("Access denied")
 	}
 
@@ -75,3 +88,51 @@ func MarkNotificationAsRead(c *fiber.Ctx) error {
 	// Redirect back to the notifications page.
 	return c.Redirect("/notifications")
 }
+
+// StreamNotifications holds open a Server-Sent Events stream that delivers the logged-in user's
+// new notifications as they are created. Each event is named "notification" and carries a JSON
+// notifications.Message, including the user's current unread count for the nav badge.
+func StreamNotifications(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	messages, unsubscribe := notifications.DefaultHub.Subscribe(uid)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		ticker := time.NewTicker(streamHeartbeat)
+		defer ticker.Stop()
+
+		// Tell the browser how long to wait before reconnecting, and flush headers right away.
+		fmt.Fprintf(w, "retry: 5000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case msg := <-messages:
+				data, err := json.Marshal(msg)
+				if err != nil {
+					log.Printf("Error marshalling notification %d: %v", msg.ID, err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", msg.ID, data)
+			case <-ticker.C:
+				fmt.Fprintf(w, ": ping\n\n")
+			}
+			// A failed flush means the client has gone away.
+			if err := w.Flush(); err != nil {
+				log.Printf("Notification stream closed for user %d", uid)
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+	"Void/pkg/oidc"
+	"Void/pkg/session"
+)
+
+// oidcLoginTimeout is how long a user has to sign in at the identity provider.
+const oidcLoginTimeout = 10 * time.Minute
+
+// BeginOIDCLogin sends the user to an identity provider to sign in. The state, nonce and PKCE
+// verifier for the attempt are kept in the session and checked when the provider redirects back.
+func BeginOIDCLogin(c *fiber.Ctx) error {
+	provider, ok := oidc.Lookup(c.Params("provider"))
+	if !ok {
+		return c.Status(404).SendString("Unknown sign-in provider")
+	}
+
+	state, nonce, verifier, err := oidc.NewLoginState()
+	if err != nil {
+		return c.Status(500).SendString("Error starting sign-in")
+	}
+	authURL, err := provider.AuthCodeURL(c.UserContext(), oidcRedirectURL(provider), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Error starting %s sign-in: %v", provider.Name, err)
+		return oidcFail(c, fiber.StatusBadGateway, provider.DisplayName+" is unavailable right now. Please try again later.")
+	}
+
+	login := session.OIDCLogin{Provider: provider.Name, State: state, Nonce: nonce, Verifier: verifier}
+	if err := session.SetOIDCLogin(c, login); err != nil {
+		return c.Status(500).SendString("Failed to save session")
+	}
+	return c.Redirect(authURL)
+}
+
+// OIDCCallback finishes a sign-in when the identity provider redirects back. The authorization
+// code is exchanged for a verified ID token, whose identity is signed in to its linked account, to
+// the account with the same verified email, or to a newly created account.
+func OIDCCallback(c *fiber.Ctx) error {
+	provider, ok := oidc.Lookup(c.Params("provider"))
+	if !ok {
+		return c.Status(404).SendString("Unknown sign-in provider")
+	}
+
+	login, err := session.TakeOIDCLogin(c, oidcLoginTimeout)
+	if err != nil || login.Provider != provider.Name ||
+		subtle.ConstantTimeCompare([]byte(login.State), []byte(c.Query("state"))) != 1 {
+		return oidcFail(c, fiber.StatusBadRequest, "Your sign-in attempt expired or was not started here. Please try again.")
+	}
+	if reason := c.Query("error"); reason != "" {
+		log.Printf("%s sign-in failed: %s %s", provider.Name, reason, c.Query("error_description"))
+		return oidcFail(c, fiber.StatusUnauthorized, "Sign-in with "+provider.DisplayName+" was cancelled or refused.")
+	}
+
+	claims, err := provider.Exchange(c.UserContext(), oidcRedirectURL(provider), c.Query("code"), login.Nonce, login.Verifier)
+	if err != nil {
+		log.Printf("Error completing %s sign-in: %v", provider.Name, err)
+		return oidcFail(c, fiber.StatusBadGateway, "We couldn't verify your "+provider.DisplayName+" sign-in. Please try again.")
+	}
+
+	user, created, err := models.SignInWithIdentity(db.DB, models.ExternalIdentity{
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Username:      claims.PreferredUsername,
+	})
+	if err != nil {
+		if models.IsValidationError(err) {
+			return oidcFail(c, fiber.StatusConflict, "Couldn't sign in with "+provider.DisplayName+": "+err.Error()+".")
+		}
+		log.Printf("Error signing in %s identity %s: %v", provider.Name, claims.Subject, err)
+		return c.Status(500).SendString("Error signing in")
+	}
+
+	if created && !user.Verified {
+		if err := user.MarkVerificationSent(db.DB); err != nil {
+			log.Printf("Error recording verification email for user %d: %v", user.ID, err)
+		}
+		go sendVerificationEmail(user, absoluteURL("/verify-email?token="+url.QueryEscape(models.NewEmailVerificationToken(user))))
+	}
+
+	return passedFirstFactor(c, user)
+}
+
+// oidcRedirectURL is where provider sends the user back to after they sign in. It must be
+// registered with the provider, and is built from BASE_URL so the request's Host header cannot
+// redirect the authorization code elsewhere.
+func oidcRedirectURL(provider *oidc.Provider) string {
+	return absoluteURL("/login/oidc/" + provider.Name + "/callback")
+}
+
+// oidcFail re-renders the login form with a sign-in error.
+func oidcFail(c *fiber.Ctx, status int, message string) error {
+	return renderLogin(c.Status(status), fiber.Map{"Error": message})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+	"Void/pkg/oidc"
+	"Void/pkg/oidc/oidctest"
+)
+
+// newOIDCTest starts a mock provider registered as "mock" and returns it with a fresh app.
+func newOIDCTest(t *testing.T) (*oidctest.Server, *fiber.App) {
+	t.Helper()
+	server := oidctest.NewServer("void", "secret")
+	t.Cleanup(server.Close)
+	oidc.Providers = []*oidc.Provider{server.Provider("mock")}
+	t.Cleanup(func() { oidc.Providers = nil })
+	return server, newTestApp(t)
+}
+
+// beginOIDCLogin starts a sign-in with the mock provider and returns the provider's authorization URL.
+func beginOIDCLogin(t *testing.T, client *testClient) *url.URL {
+	t.Helper()
+	resp := client.get("/login/oidc/mock")
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("GET /login/oidc/mock: status %d, want 302", resp.StatusCode)
+	}
+	authURL, err := resp.Location()
+	if err != nil {
+		t.Fatalf("no authorization redirect: %v", err)
+	}
+	return authURL
+}
+
+// oidcSignIn signs id in at server for a login the client started and returns the callback
+// request the provider redirects back to.
+func oidcSignIn(t *testing.T, server *oidctest.Server, authURL *url.URL, id oidctest.Identity) *url.URL {
+	t.Helper()
+	server.SetIdentity(id)
+	callback, err := server.Authorize(authURL.String())
+	if err != nil {
+		t.Fatalf("signing in at the provider: %v", err)
+	}
+	return callback
+}
+
+func userCount(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := db.DB.Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOIDCLoginCreatesAccount(t *testing.T) {
+	server, app := newOIDCTest(t)
+	client := newTestClient(t, app)
+
+	authURL := beginOIDCLogin(t, client)
+	q := authURL.Query()
+	if got, want := q.Get("redirect_uri"), testBaseURL+"/login/oidc/mock/callback"; got != want {
+		t.Errorf("redirect_uri = %q, want %q", got, want)
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("authorization URL has no S256 PKCE challenge: %s", authURL)
+	}
+
+	callback := oidcSignIn(t, server, authURL, oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice",
+	})
+	resp := client.get(callback.RequestURI())
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get(fiber.HeaderLocation) != "/" {
+		t.Fatalf("callback: status %d, location %q; want a redirect to /", resp.StatusCode, resp.Header.Get(fiber.HeaderLocation))
+	}
+	if server.LastVerifier() == "" {
+		t.Error("token request carried no PKCE verifier")
+	}
+
+	var user models.User
+	if err := db.DB.Where("username = ?", "alice").First(&user).Error; err != nil {
+		t.Fatalf("no account created: %v", err)
+	}
+	if resp := client.get("/"); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("GET / after sign-in: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	server, app := newOIDCTest(t)
+	client := newTestClient(t, app)
+
+	callback := oidcSignIn(t, server, beginOIDCLogin(t, client), oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true,
+	})
+	q := callback.Query()
+	q.Set("state", "forged")
+	callback.RawQuery = q.Encode()
+
+	if resp := client.get(callback.RequestURI()); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("callback with a forged state: status %d, want 400", resp.StatusCode)
+	}
+	if n := userCount(t); n != 0 {
+		t.Errorf("%d users created, want 0", n)
+	}
+}
+
+func TestOIDCCallbackRejectsLoginStartedElsewhere(t *testing.T) {
+	server, app := newOIDCTest(t)
+	victim := newTestClient(t, app)
+	attacker := newTestClient(t, app)
+
+	// The attacker's own callback URL, delivered to a victim who never started a sign-in.
+	callback := oidcSignIn(t, server, beginOIDCLogin(t, attacker), oidctest.Identity{
+		Subject: "attacker-sub", Email: "attacker@example.com", EmailVerified: true,
+	})
+	if resp := victim.get(callback.RequestURI()); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("callback without a sign-in in the session: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	server, app := newOIDCTest(t)
+	client := newTestClient(t, app)
+
+	callback := oidcSignIn(t, server, beginOIDCLogin(t, client), oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true, Nonce: "replayed",
+	})
+	if resp := client.get(callback.RequestURI()); resp.StatusCode != fiber.StatusBadGateway {
+		t.Errorf("callback with a mismatched nonce: status %d, want 502", resp.StatusCode)
+	}
+	if n := userCount(t); n != 0 {
+		t.Errorf("%d users created, want 0", n)
+	}
+}
+
+func TestOIDCLoginLinksOnlyVerifiedEmail(t *testing.T) {
+	server, app := newOIDCTest(t)
+	alice := createUser(t, "alice", "alice@example.com")
+
+	// An unverified address must not take over the existing account.
+	client := newTestClient(t, app)
+	callback := oidcSignIn(t, server, beginOIDCLogin(t, client), oidctest.Identity{
+		Subject: "mallory-sub", Email: "alice@example.com", EmailVerified: false,
+	})
+	if resp := client.get(callback.RequestURI()); resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("unverified email: status %d, want 409", resp.StatusCode)
+	}
+	if resp := client.get("/"); resp.StatusCode != fiber.StatusFound {
+		t.Errorf("GET / after refused sign-in: status %d, want a redirect to login", resp.StatusCode)
+	}
+
+	client = newTestClient(t, app)
+	callback = oidcSignIn(t, server, beginOIDCLogin(t, client), oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", EmailVerified: "true",
+	})
+	if resp := client.get(callback.RequestURI()); resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("verified email: status %d, want 302", resp.StatusCode)
+	}
+	var identity models.UserIdentity
+	if err := db.DB.Where("provider = ? AND subject = ?", "mock", "alice-sub").First(&identity).Error; err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if identity.UserID != alice.ID {
+		t.Errorf("identity linked to user %d, want %d", identity.UserID, alice.ID)
+	}
+}
+
+func TestOIDCLoginRespectsRegistrationMode(t *testing.T) {
+	for _, mode := range []string{models.RegistrationClosed, models.RegistrationInvite} {
+		t.Run(mode, func(t *testing.T) {
+			server, app := newOIDCTest(t)
+			if err := models.SetRegistrationMode(db.DB, mode); err != nil {
+				t.Fatal(err)
+			}
+
+			client := newTestClient(t, app)
+			callback := oidcSignIn(t, server, beginOIDCLogin(t, client), oidctest.Identity{
+				Subject: "new-sub", Email: "new@example.com", EmailVerified: true,
+			})
+			if resp := client.get(callback.RequestURI()); resp.StatusCode != http.StatusConflict {
+				t.Errorf("status %d, want 409", resp.StatusCode)
+			}
+			if n := userCount(t); n != 0 {
+				t.Errorf("%d users created, want 0", n)
+			}
+		})
+	}
+}
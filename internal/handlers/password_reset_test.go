@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/pkg/mailer"
+)
+
+// resetLinkPattern matches the reset link in a password reset email.
+var resetLinkPattern = regexp.MustCompile(`https?://\S+/reset-password\?token=\S+`)
+
+// waitForMail waits for the single message written to dir by a LogMailer and returns it.
+func waitForMail(t *testing.T, dir string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) > 0 {
+			raw, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(raw)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no email was sent")
+	return ""
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	app := newTestApp(t)
+	createUser(t, "alice", "alice@example.com")
+	dir := t.TempDir()
+	mailer.Default = &mailer.LogMailer{Dir: dir}
+	t.Cleanup(func() { mailer.Default = discardMailer{} })
+
+	// Signed in elsewhere before the reset.
+	other := newTestClient(t, app)
+	other.login("alice@example.com")
+
+	// The emailed link must point at BASE_URL even when the request names another host.
+	client := newTestClient(t, app)
+	req := url.Values{"email": {"alice@example.com"}, "_csrf": {client.csrfToken("/forgot-password")}}
+	resp := client.postForm("http://attacker.example/forgot-password", req)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("POST /forgot-password: status %d, want 200", resp.StatusCode)
+	}
+	link := resetLinkPattern.FindString(waitForMail(t, dir))
+	resetURL, err := url.Parse(link)
+	if err != nil || link == "" {
+		t.Fatalf("no reset link in the email: %q", link)
+	}
+	if got := resetURL.Scheme + "://" + resetURL.Host; got != testBaseURL {
+		t.Errorf("reset link points at %s, want %s", got, testBaseURL)
+	}
+	token := resetURL.Query().Get("token")
+
+	form := url.Values{
+		"token": {token}, "password": {"new password"}, "password_confirmation": {"new password"},
+		"_csrf": {client.csrfToken(resetURL.RequestURI())},
+	}
+	resp = client.postForm("/reset-password", form)
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get(fiber.HeaderLocation) != "/login?reset=1" {
+		t.Fatalf("POST /reset-password: status %d, location %q", resp.StatusCode, resp.Header.Get(fiber.HeaderLocation))
+	}
+
+	// The link is used up, and the session from before the reset is signed out.
+	form.Set("_csrf", client.csrfToken(resetURL.RequestURI()))
+	if resp := client.postForm("/reset-password", form); resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("reusing the reset link: status %d, want 422", resp.StatusCode)
+	}
+	if resp := other.get("/"); resp.StatusCode != fiber.StatusFound {
+		t.Errorf("GET / with a session from before the reset: status %d, want a redirect to login", resp.StatusCode)
+	}
+
+	resp = client.postForm("/login", url.Values{
+		"email": {"alice@example.com"}, "password": {"new password"}, "_csrf": {client.csrfToken("/login")},
+	})
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get(fiber.HeaderLocation) != "/" {
+		t.Errorf("logging in with the new password: status %d, location %q", resp.StatusCode, resp.Header.Get(fiber.HeaderLocation))
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/policy"
+	"Void/internal/services/notifications"
+)
+
+// moderationQueueLimit is how many reports the moderation queue shows at once.
+const moderationQueueLimit = 50
+
+// RegisterReportRoutes registers the routes for reporting content and for the moderators' report queue.
+func RegisterReportRoutes(app *fiber.App) {
+	report := app.Group("/report", middleware.GetUserFromSession, middleware.RequireLogin)
+	report.Get("/:type/:id", ShowReportForm)
+	report.Post("/:type/:id", CreateReport)
+
+	moderation := app.Group("/moderation", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireRole(models.RoleModerator))
+	moderation.Get("/reports", ShowReports)
+	moderation.Post("/reports/:id/resolve", ResolveReport)
+}
+
+// reportTarget is the shout, echo or user a report form is about.
+type reportTarget struct {
+	Type     string      // One of the models.ReportTarget* constants
+	ID       uint        // The ID of the shout, echo or user
+	Username string      // The reported user, or the author of the reported content
+	Content  string      // The reported shout's or echo's content; empty for users
+	Link     string      // Where to go back to after reporting
+	shoutID  uint        // The reported shout, or the shout the reported echo is on; zero for users
+	resource interface{} // The model value, for the policy check
+}
+
+// findReportTarget loads the target named by the :type and :id route parameters.
+func findReportTarget(c *fiber.Ctx) (reportTarget, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return reportTarget{}, fiber.NewError(fiber.StatusBadRequest, "Invalid report target")
+	}
+	target := reportTarget{Type: c.Params("type"), ID: uint(id)}
+
+	notFound := fiber.NewError(fiber.StatusNotFound, "Nothing to report here")
+	switch target.Type {
+	case models.ReportTargetShout:
+		var shout models.Shout
+		if err := db.DB.Preload("User").First(&shout, id).Error; err != nil {
+			return target, notFound
+		}
+		target.Username, target.Content, target.resource = shout.User.Username, shout.Content, shout
+		target.Link = fmt.Sprintf("/global/shout/%d", shout.ID)
+		target.shoutID = shout.ID
+	case models.ReportTargetEcho:
+		var echo models.Echo
+		if err := db.DB.Preload("User").First(&echo, id).Error; err != nil {
+			return target, notFound
+		}
+		target.Username, target.Content, target.resource = echo.User.Username, echo.Content, echo
+		target.Link = fmt.Sprintf("/global/shout/%d#echo-%d", echo.ShoutID, echo.ID)
+		target.shoutID = echo.ShoutID
+	case models.ReportTargetUser:
+		var user models.User
+		if err := db.DB.First(&user, id).Error; err != nil {
+			return target, notFound
+		}
+		target.Username, target.resource = user.Username, user
+		target.Link = "/users/" + user.Username
+	default:
+		return target, notFound
+	}
+	return target, nil
+}
+
+// ShowReportForm renders the form for reporting a shout, echo or user.
+func ShowReportForm(c *fiber.Ctx) error {
+	target, err := findReportTarget(c)
+	if err != nil {
+		return err
+	}
+	if !can(c, policy.Report, target.resource) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString("You can't report yourself")
+	}
+	return renderReportForm(c, target, fiber.Map{})
+}
+
+// CreateReport files the logged-in user's report from the reason and details fields.
+func CreateReport(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	target, err := findReportTarget(c)
+	if err != nil {
+		return err
+	}
+	reason, details := c.FormValue("reason"), c.FormValue("details")
+	if _, err := models.FileReport(db.DB, uid, target.Type, target.ID, reason, details); err != nil {
+		if models.IsValidationError(err) {
+			return renderReportForm(c.Status(fiber.StatusUnprocessableEntity), target, fiber.Map{
+				"Error":   err.Error(),
+				"Reason":  reason,
+				"Details": details,
+			})
+		}
+		log.Printf("Error filing report by user %d: %v", uid, err)
+		return c.Status(500).SendString("Error filing report")
+	}
+	return renderReportForm(c, target, fiber.Map{
+		"Message": "Thanks for letting us know. A moderator will review your report, and you'll get a notification when they do.",
+	})
+}
+
+// renderReportForm renders the report page for target merged with extra data such as an error.
+func renderReportForm(c *fiber.Ctx, target reportTarget, data fiber.Map) error {
+	uid := c.Locals("UserID").(uint)
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	data["UserID"] = uid
+	data["NotificationCount"] = count
+	data["Target"] = target
+	if target.shoutID != 0 {
+		data["Mentioned"] = mentionedUsernames(target.shoutID)
+	}
+	data["Reasons"] = models.ReportReasons
+	data["MaxDetails"] = models.MaxReportDetails
+	return c.Render("report", data, "layouts/main")
+}
+
+// ShowReports renders the moderation queue, showing the reports in the triage state given by the
+// "status" query parameter, open by default.
+func ShowReports(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	status := c.Query("status", models.ReportOpen)
+	known := false
+	for _, s := range models.ReportStatuses {
+		known = known || s == status
+	}
+	if !known {
+		return c.Status(400).SendString("Unknown report status")
+	}
+
+	reports, err := models.ReportsWithStatus(db.DB, status, moderationQueueLimit)
+	if err != nil {
+		log.Printf("Error fetching %s reports: %v", status, err)
+		return c.Status(500).SendString("Error loading reports")
+	}
+	actor, err := currentUser(c)
+	if err != nil {
+		return c.SendString("User not found")
+	}
+
+	var ids []uint
+	for _, report := range reports {
+		if report.Shout != nil {
+			ids = append(ids, report.Shout.ID)
+		}
+		if report.Echo != nil {
+			ids = append(ids, report.Echo.ShoutID)
+		}
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("moderation_reports", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Actor":             actor,
+		"Status":            status,
+		"Statuses":          models.ReportStatuses,
+		"Reports":           reports,
+		"Mentioned":         mentionedUsernames(ids...),
+		"Limit":             moderationQueueLimit,
+	}, "layouts/main")
+}
+
+// ResolveReport applies the action form field to an open report: "hide" removes the reported
+// content, "suspend" suspends its author for the number of days in the "days" field, and "dismiss"
+// closes the report. Everyone who reported the same thing is notified of the outcome.
+func ResolveReport(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	var report models.Report
+	if err := db.DB.Preload("TargetUser").First(&report, c.Params("id")).Error; err != nil {
+		return c.Status(404).SendString("Report not found")
+	}
+
+	action := c.FormValue("action")
+	var until time.Time
+	if action == models.ReportActionSuspend {
+		if !can(c, policy.RestrictUser, report.TargetUser) {
+			return c.Status(403).SendString("You can't suspend this user")
+		}
+		days, err := strconv.Atoi(c.FormValue("days"))
+		if err != nil || days <= 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString("Enter the number of days to suspend for")
+		}
+		until = time.Now().AddDate(0, 0, days)
+	}
+
+	resolved, err := models.ResolveReport(db.DB, &report, uid, action, until)
+	if err != nil {
+		if models.IsValidationError(err) {
+			return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+		}
+		log.Printf("Error resolving report %d: %v", report.ID, err)
+		return c.Status(500).SendString("Error resolving report")
+	}
+	notifications.SendReportResolvedNotifications(resolved)
+
+	return c.Redirect("/moderation/reports")
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/middleware"
+	"Void/internal/models"
+	"Void/internal/services/search"
+)
+
+// searchLimit is the maximum number of results shown for each kind of match.
+const searchLimit = 20
+
+// RegisterSearchRoutes registers the public search page.
+func RegisterSearchRoutes(app *fiber.App) {
+	app.Get("/search", middleware.GetUserFromSession, Search)
+}
+
+// Search renders ranked shout, echo and user matches for the "q" query parameter.
+// Queries support "quoted phrases", from:username and #tag filters. No authentication is required.
+func Search(c *fiber.Ctx) error {
+	raw := c.Query("q")
+	uid := c.Locals("UserID").(uint)
+	results, err := search.Search(search.Parse(raw), uid, searchLimit)
+
+	var searchError string
+	if err != nil {
+		if errors.Is(err, search.ErrDisabled) {
+			searchError = err.Error()
+		} else {
+			log.Printf("Search error for %q: %v", raw, err)
+			searchError = "Something went wrong running that search."
+		}
+	}
+
+	if uid == 0 {
+		return c.Render("search", fiber.Map{
+			"Query":   raw,
+			"Results": results,
+			"Error":   searchError,
+			"UserID":  nil,
+		}, "layouts/main")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("search", fiber.Map{
+		"Query":             raw,
+		"Results":           results,
+		"Error":             searchError,
+		"UserID":            uid,
+		"NotificationCount": count,
+	}, "layouts/main")
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+	"Void/pkg/session"
+)
+
+// ShowSessions lists the devices the logged-in user is signed in on.
+func ShowSessions(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	sessions, err := models.ActiveUserSessions(db.DB, uid, session.Lifetime)
+	if err != nil {
+		log.Printf("Error fetching sessions for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error loading sessions")
+	}
+	current, _ := session.ID(c)
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("sessions", fiber.Map{
+		"UserID":            uid,
+		"NotificationCount": count,
+		"Sessions":          sessions,
+		"CurrentSessionID":  current,
+	}, "layouts/main")
+}
+
+// RevokeSession signs the logged-in user out of one of their sessions.
+func RevokeSession(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(400).SendString("Invalid session")
+	}
+	sessionID, err := models.RevokeUserSession(db.DB, uid, uint(id))
+	if err != nil {
+		return c.Status(404).SendString("Session not found")
+	}
+	if err := session.Revoke(sessionID); err != nil {
+		log.Printf("Error deleting session %d: %v", id, err)
+	}
+
+	return c.Redirect("/profile/sessions")
+}
+
+// RevokeOtherSessions signs the logged-in user out everywhere except the current session.
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	current, err := session.ID(c)
+	if err != nil {
+		return c.Status(500).SendString("Error revoking sessions")
+	}
+	sessionIDs, err := models.RevokeOtherUserSessions(db.DB, uid, current)
+	if err != nil {
+		log.Printf("Error revoking sessions for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error revoking sessions")
+	}
+	for _, sessionID := range sessionIDs {
+		if err := session.Revoke(sessionID); err != nil {
+			log.Printf("Error deleting session for user %d: %v", uid, err)
+		}
+	}
+
+	return c.Redirect("/profile/sessions")
+}
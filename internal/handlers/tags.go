@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/middleware"
+	"Void/internal/models"
+)
+
+const (
+	// trendingWindow is how far back shouts are counted when computing trending tags.
+	trendingWindow = 24 * time.Hour
+
+	// trendingLimit is the maximum number of tags shown in the trending sidebar.
+	trendingLimit = 10
+)
+
+// RegisterTagRoutes registers the public hashtag browsing routes.
+func RegisterTagRoutes(app *fiber.App) {
+	app.Get("/tags/:name", middleware.GetUserFromSession, GetTag)
+}
+
+// GetTag lists every shout tagged with the given hashtag, newest first. No authentication is required.
+func GetTag(c *fiber.Ctx) error {
+	name := strings.ToLower(strings.TrimPrefix(c.Params("name"), "#"))
+
+	shouts, page, err := findShoutPage(c, withoutMuted(c, db.DB.Preload("User").
+		Where("id IN (?)", models.TaggedShoutIDs(db.DB, name))))
+	if err != nil {
+		return err
+	}
+	mentioned := mentionedUsernames(shoutIDs(shouts)...)
+
+	uid := c.Locals("UserID").(uint)
+	if uid == 0 {
+		return c.Render("tag", fiber.Map{
+			"Tag":       name,
+			"Shouts":    shouts,
+			"Mentioned": mentioned,
+			"Page":      page,
+			"UserID":    nil,
+		}, "layouts/main")
+	}
+
+	var count int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
+
+	return c.Render("tag", fiber.Map{
+		"Tag":               name,
+		"Shouts":            shouts,
+		"Mentioned":         mentioned,
+		"Page":              page,
+		"UserID":            uid,
+		"NotificationCount": count,
+	}, "layouts/main")
+}
+
+// trendingTags returns the trending tags for the sidebar, logging rather than failing on errors.
+func trendingTags() []models.TrendingTag {
+	trending, err := models.TrendingTags(db.DB, time.Now().Add(-trendingWindow), trendingLimit)
+	if err != nil {
+		log.Printf("Error fetching trending tags: %v", err)
+	}
+	return trending
+}
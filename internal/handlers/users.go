@@ -4,6 +4,7 @@ import (
 	"Void/internal/db"
 	"Void/internal/middleware"
 	"Void/internal/models"
+	"Void/internal/services/notifications"
 	"fmt"
 	"github.com/disintegration/imaging"
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +17,23 @@ func RegisterUserRoutes(app *fiber.App) {
 	app.Get("/users/:username", middleware.GetUserFromSession, GetProfile)
 	app.Get("/profile/edit", middleware.GetUserFromSession, ShowEditProfile)
 	app.Post("/profile/edit", middleware.GetUserFromSession, UpdateProfile)
+	app.Post("/users/:username/follow", middleware.GetUserFromSession, middleware.RequireLogin, FollowUser)
+	app.Post("/users/:username/unfollow", middleware.GetUserFromSession, middleware.RequireLogin, UnfollowUser)
+	app.Post("/users/:username/notify", middleware.GetUserFromSession, middleware.RequireLogin, ToggleFollowNotifications)
+	app.Post("/users/:username/block", middleware.GetUserFromSession, middleware.RequireLogin, BlockUser)
+	app.Post("/users/:username/unblock", middleware.GetUserFromSession, middleware.RequireLogin, UnblockUser)
+	app.Post("/users/:username/mute", middleware.GetUserFromSession, middleware.RequireLogin, MuteUser)
+	app.Post("/users/:username/unmute", middleware.GetUserFromSession, middleware.RequireLogin, UnmuteUser)
+	app.Get("/profile/blocks", middleware.GetUserFromSession, middleware.RequireLogin, ShowBlocks)
+	app.Post("/profile/tokens", middleware.GetUserFromSession, middleware.RequireLogin, CreateAccessToken)
+	app.Post("/profile/tokens/:id/revoke", middleware.GetUserFromSession, middleware.RequireLogin, RevokeAccessToken)
+	app.Post("/profile/2fa/setup", middleware.GetUserFromSession, middleware.RequireLogin, BeginTwoFactorSetup)
+	app.Post("/profile/2fa/enable", middleware.GetUserFromSession, middleware.RequireLogin, EnableTwoFactor)
+	app.Post("/profile/2fa/disable", middleware.GetUserFromSession, middleware.RequireLogin, DisableTwoFactor)
+	app.Post("/profile/2fa/recovery-codes", middleware.GetUserFromSession, middleware.RequireLogin, RegenerateRecoveryCodes)
+	app.Get("/profile/sessions", middleware.GetUserFromSession, middleware.RequireLogin, ShowSessions)
+	app.Post("/profile/sessions/revoke-others", middleware.GetUserFromSession, middleware.RequireLogin, RevokeOtherSessions)
+	app.Post("/profile/sessions/:id/revoke", middleware.GetUserFromSession, middleware.RequireLogin, RevokeSession)
 }
 
 // GetProfile handles HTTP GET requests to retrieve a user profile based on the provided username parameter.
@@ -31,9 +49,21 @@ func GetProfile(c *fiber.Ctx) error {
 		return c.SendString("User not found")
 	}
 
-	var shouts []models.Shout
-	if err := db.DB.Where("user_id = ?", user.ID).Order("created_at desc").Find(&shouts).Error; err != nil {
-		log.Printf("Error fetching shouts for user %s: %v", username, err)
+	shouts, page, err := findShoutPage(c, db.DB.Where("user_id = ?", user.ID))
+	if err != nil {
+		return err
+	}
+
+	// Load the users following this profile and the users it follows.
+	var followers []models.User
+	if err := db.DB.Where("id IN (?)", db.DB.Model(&models.Follow{}).Select("follower_id").Where("followee_id = ?", user.ID)).
+		Find(&followers).Error; err != nil {
+		log.Printf("Error fetching followers for user %s: %v", username, err)
+	}
+
+	var following []models.User
+	if err := db.DB.Where("id IN (?)", models.FolloweeIDs(db.DB, user.ID)).Find(&following).Error; err != nil {
+		log.Printf("Error fetching following for user %s: %v", username, err)
 	}
 
 	var count int64
@@ -44,6 +74,18 @@ func GetProfile(c *fiber.Ctx) error {
 		"User":              user,
 		"UserID":            uid,
 		"Shouts":            shouts,
+		"Mentioned":         mentionedUsernames(shoutIDs(shouts)...),
+		"Page":              page,
+		"Followers":         followers,
+		"Following":         following,
+		"FollowerCount":     len(followers),
+		"FollowingCount":    len(following),
+		"IsOwnProfile":      uid == user.ID,
+		"IsFollowing":       uid != 0 && models.IsFollowing(db.DB, uid, user.ID),
+		"IsNotifying":       uid != 0 && models.IsNotifying(db.DB, uid, user.ID),
+		"IsBlocked":         uid != 0 && models.IsBlocked(db.DB, uid, user.ID),
+		"IsBlocking":        uid != 0 && models.IsBlocking(db.DB, uid, user.ID),
+		"IsMuting":          uid != 0 && models.IsMuting(db.DB, uid, user.ID),
 		"NotificationCount": count,
 	}, "layouts/main")
 
@@ -51,6 +93,16 @@ func GetProfile(c *fiber.Ctx) error {
 
 // ShowEditProfile renders the edit profile form.
 func ShowEditProfile(c *fiber.Ctx) error {
+	return renderEditProfile(c, fiber.Map{})
+}
+
+// recentFailedLoginsShown is the number of failed login attempts listed on the edit profile page.
+const recentFailedLoginsShown = 20
+
+// renderEditProfile renders the edit profile page, including the user's access tokens,
+// two-factor settings and recent failed logins, merged with any extra template data such as a
+// newly created token or an error.
+func renderEditProfile(c *fiber.Ctx, data fiber.Map) error {
 	uid := c.Locals("UserID").(uint)
 
 	var user models.User
@@ -58,14 +110,71 @@ func ShowEditProfile(c *fiber.Ctx) error {
 		return c.SendString("User not found")
 	}
 
+	var tokens []models.AccessToken
+	if err := db.DB.Where("user_id = ?", uid).Order("created_at desc").Find(&tokens).Error; err != nil {
+		log.Printf("Error fetching access tokens for user %d: %v", uid, err)
+	}
+
+	failedLogins, err := models.RecentFailedLogins(db.DB, uid, recentFailedLoginsShown)
+	if err != nil {
+		log.Printf("Error fetching failed logins for user %d: %v", uid, err)
+	}
+
 	var count int64
 	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
 
-	return c.Render("edit_profile", fiber.Map{
-		"User":              user,
-		"UserID":            uid,
-		"NotificationCount": count,
-	}, "layouts/main")
+	data["User"] = user
+	data["UserID"] = uid
+	data["NotificationCount"] = count
+	data["AccessTokens"] = tokens
+	data["Scopes"] = models.AllScopes
+	data["FailedLogins"] = failedLogins
+	for key, value := range twoFactorData(user) {
+		data[key] = value
+	}
+	return c.Render("edit_profile", data, "layouts/main")
+}
+
+// CreateAccessToken creates a personal access token from the name and scope checkboxes on the
+// edit profile page, then shows its plaintext value once.
+func CreateAccessToken(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	var scopes []string
+	for _, scope := range models.AllScopes {
+		if c.FormValue("scope_"+scope) == "on" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	token, plaintext, err := models.NewAccessToken(uid, c.FormValue("name"), scopes)
+	if err == nil {
+		err = db.DB.Create(token).Error
+	}
+	if err != nil {
+		if models.IsValidationError(err) {
+			return renderEditProfile(c, fiber.Map{"TokenError": err.Error()})
+		}
+		log.Printf("Error creating access token for user %d: %v", uid, err)
+		return c.Status(500).SendString("Error creating access token")
+	}
+
+	return renderEditProfile(c, fiber.Map{"NewToken": plaintext, "NewTokenName": token.Name})
+}
+
+// RevokeAccessToken revokes one of the logged-in user's personal access tokens.
+func RevokeAccessToken(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+
+	tokenID, err := c.ParamsInt("id")
+	if err != nil || tokenID <= 0 {
+		return c.Status(400).SendString("Invalid token")
+	}
+	if err := models.RevokeAccessToken(db.DB, uid, uint(tokenID)); err != nil {
+		return c.Status(404).SendString("Token not found")
+	}
+
+	return c.Redirect("/profile/edit")
 }
 
 // UpdateProfile processes the form submission to update avatar and bio.
@@ -123,3 +232,68 @@ func UpdateProfile(c *fiber.Ctx) error {
 
 	return c.Redirect("/users/" + user.Username)
 }
+
+// FollowUser makes the logged-in user follow the user identified by the username parameter.
+func FollowUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	username := c.Params("username")
+
+	var user models.User
+	if err := db.DB.First(&user, "username = ?", username).Error; err != nil {
+		return c.Status(404).SendString("User not found")
+	}
+
+	follow := models.Follow{
+		FollowerID: uid,
+		FolloweeID: user.ID,
+	}
+	alreadyFollowing := models.IsFollowing(db.DB, uid, user.ID)
+	if err := follow.Create(db.DB); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+
+	if !alreadyFollowing {
+		var follower models.User
+		if err := db.DB.First(&follower, uid).Error; err == nil {
+			notifications.SendFollowNotification(follower, user.ID)
+		}
+	}
+
+	return c.Redirect("/users/" + user.Username)
+}
+
+// UnfollowUser removes the logged-in user's follow of the user identified by the username parameter.
+func UnfollowUser(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	username := c.Params("username")
+
+	var user models.User
+	if err := db.DB.First(&user, "username = ?", username).Error; err != nil {
+		return c.Status(404).SendString("User not found")
+	}
+
+	if err := models.Unfollow(db.DB, uid, user.ID); err != nil {
+		return c.Status(500).SendString("Failed to unfollow user")
+	}
+
+	return c.Redirect("/users/" + user.Username)
+}
+
+// ToggleFollowNotifications turns the "notify me of every post" bell on or off for a followed user.
+// The desired state is read from the "notify" form value ("on" enables it, anything else disables it).
+func ToggleFollowNotifications(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	username := c.Params("username")
+
+	var user models.User
+	if err := db.DB.First(&user, "username = ?", username).Error; err != nil {
+		return c.Status(404).SendString("User not found")
+	}
+
+	notify := c.FormValue("notify") == "on"
+	if err := models.SetNotify(db.DB, uid, user.ID, notify); err != nil {
+		return c.Status(400).SendString(err.Error())
+	}
+
+	return c.Redirect("/users/" + user.Username)
+}
@@ -2,32 +2,52 @@ package handlers
 
 import (
 	"Void/internal/events"
+	"fmt"
 	"log"
+	"time"
 
 	"Void/internal/db"
 	"Void/internal/middleware"
 	"Void/internal/models"
+	"Void/internal/pagination"
+	"Void/internal/policy"
+	"Void/internal/services/feed"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// feedPingInterval is how often the echo chamber socket is pinged to keep it alive.
+	feedPingInterval = 30 * time.Second
+
+	// feedPongWait is how long a socket may go without answering a ping before it is closed.
+	feedPongWait = 2 * feedPingInterval
+
+	// feedWriteWait bounds how long a single write to the socket may block.
+	feedWriteWait = 10 * time.Second
 )
 
 // RegisterVoidRoutes configures routes for both global and authenticated functionalities for the app.
 func RegisterVoidRoutes(app *fiber.App) {
 	// Register these FIRST - before the auth group
 	app.Get("/echo-chamber", middleware.GetUserFromSession, GetGlobalFeed)
+	app.Get("/echo-chamber/ws", RequireWebSocket, middleware.GetUserFromSession, websocket.New(StreamGlobalFeed))
 	app.Get("/global/shout/:id", middleware.GetUserFromSession, GetGlobalShout)
-	app.Post("/global/shout/:id/echo", middleware.GetUserFromSession, CreateGlobalEcho)
+	app.Post("/global/shout/:id/echo", middleware.GetUserFromSession, middleware.RequireLogin, middleware.RequireVerified, CreateGlobalEcho)
 	// Then register the auth group
 	authGroup := app.Group("/", middleware.GetUserFromSession, middleware.RequireLogin)
 	authGroup.Get("/", GetShouts)
-	authGroup.Post("/shout", CreateShout)
+	authGroup.Post("/shout", middleware.RequireVerified, CreateShout)
 	authGroup.Get("/shout/:id", GetShout)
-	authGroup.Post("/shout/:id/echo", CreateEcho)
+	authGroup.Post("/shout/:id/echo", middleware.RequireVerified, CreateEcho)
 	authGroup.Get("/shout/:id/edit", EditShoutForm)
 	authGroup.Post("/shout/:id/update", UpdateShout)
 	authGroup.Post("/shout/:id/delete", DeleteShout)
+	authGroup.Post("/echo/:id/delete", DeleteEcho)
 }
 
-// GetShouts retrieves all shouts for the logged-in user.
+// GetShouts retrieves the home timeline for the logged-in user: their own shouts plus shouts from accounts they follow.
 func GetShouts(c *fiber.Ctx) error {
 	log.Println("=== GetShouts handler started ===")
 
@@ -40,11 +60,11 @@ func GetShouts(c *fiber.Ctx) error {
 	log.Printf("User from session: %v", user)
 
 	log.Printf("UserID from session: %v", uid)
-	var shouts []models.Shout
-	result := db.DB.Preload("Echoes").Preload("User").Where("user_id = ?", uid).Find(&shouts)
-	if result.Error != nil {
-		log.Printf("Database error: %v", result.Error)
-		return c.Status(500).SendString("Database error")
+	// The home timeline merges the user's own shouts with shouts from accounts they follow.
+	shouts, page, err := findShoutPage(c, withoutMuted(c, db.DB.Preload("User").
+		Where("user_id = ? OR user_id IN (?)", uid, models.FolloweeIDs(db.DB, uid))))
+	if err != nil {
+		return err
 	}
 
 	log.Printf("Found %d shouts for user %d", len(shouts), uid)
@@ -54,6 +74,8 @@ func GetShouts(c *fiber.Ctx) error {
 	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
 	return c.Render("index", fiber.Map{
 		"Shouts":            shouts,
+		"Mentioned":         mentionedUsernames(shoutIDs(shouts)...),
+		"Page":              page,
 		"UserID":            uid,
 		"User":              user,
 		"NotificationCount": count,
@@ -62,8 +84,7 @@ func GetShouts(c *fiber.Ctx) error {
 
 // CreateShout processes a request to create a new shout and persist it in the database, while managing related operations.
 // Retrieves the user ID from the session and validates the input shout content.
-// Saves the new shout to the database and populates its related user data for enriching event publication.
-// Converts the shout into an event payload and publishes the event using the configured messaging mechanism.
+// Shout.Create saves the shout and its mentions, populates its user data and publishes the shout event.
 // Redirects the user after successful creation or handles errors appropriately during the process.
 func CreateShout(c *fiber.Ctx) error {
 	uid := c.Locals("UserID").(uint)
@@ -78,22 +99,8 @@ func CreateShout(c *fiber.Ctx) error {
 		UserID:  uid,
 	}
 
-	// Save the shout to the database.
-	if err := db.DB.Create(&shout).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
-	}
-
-	// Load the associated user (to fill in Username, etc.)
-	if err := db.DB.First(&shout.User, uid).Error; err != nil {
-		log.Printf("Failed to load user: %v", err)
-		// Continue even if this fails.
-	}
-
-	// Convert the shout to its event payload.
-	eventPayload := shout.ToEvent()
-
-	// Publish the event using the interface-based function.
-	if err := events.PublishShoutEvent(eventPayload); err != nil {
+	// Save the shout and its mentions, then publish the event.
+	if err := shout.Create(db.DB); err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
@@ -106,18 +113,22 @@ func GetShout(c *fiber.Ctx) error {
 
 	id := c.Params("id")
 	var shout models.Shout
-	result := db.DB.Preload("Echoes").First(&shout, id)
+	result := db.DB.Preload("Echoes", "user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, uid)).Preload("Echoes.User").
+		First(&shout, id)
 	if result.Error != nil {
 		return c.SendStatus(404)
 	}
-	if shout.UserID != uid {
+	if !can(c, policy.ManageShout, shout) {
 		return c.Status(403).SendString("Access denied")
 	}
+	actor, _ := currentUser(c)
 	var count int64
 	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", uid, false).Count(&count)
 
 	return c.Render("shout", fiber.Map{
 		"Shout":             shout,
+		"Mentioned":         mentionedUsernames(shout.ID),
+		"Actor":             actor,
 		"UserID":            uid,
 		"NotificationCount": count,
 	}, "layouts/main")
@@ -133,7 +144,7 @@ func CreateEcho(c *fiber.Ctx) error {
 		return c.Status(404).SendString("Shout not found")
 	}
 
-	if shout.UserID != uid {
+	if !can(c, policy.ManageShout, shout) {
 		return c.Status(403).SendString("Unauthorized")
 	}
 
@@ -145,11 +156,13 @@ func CreateEcho(c *fiber.Ctx) error {
 	echo := models.Echo{
 		Content: content,
 		ShoutID: shout.ID,
+		UserID:  uid,
 	}
 
 	if err := echo.Create(db.DB); err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
+	publishEchoEvent(&echo, shout)
 
 	return c.Redirect("/shout/" + id)
 }
@@ -157,20 +170,22 @@ func CreateEcho(c *fiber.Ctx) error {
 // GetGlobalFeed retrieves all global shouts.
 func GetGlobalFeed(c *fiber.Ctx) error {
 	log.Println("GetGlobalFeed handler started")
-	var shouts []models.Shout
-	result := db.DB.Preload("Echoes").Preload("User").Order("created_at desc").Find(&shouts)
-	if result.Error != nil {
-		log.Printf("Database error: %v", result.Error)
-		return c.Status(500).SendString("Database error")
+	shouts, page, err := findShoutPage(c, withoutMuted(c, db.DB.Preload("User")))
+	if err != nil {
+		return err
 	}
 	log.Printf("Found %d global shouts", len(shouts))
+	mentioned := mentionedUsernames(shoutIDs(shouts)...)
 
 	uid := c.Locals("UserID").(uint)
 	if uid == 0 {
 		// If no valid user is found, render with nil UserID
 		return c.Render("echo_chamber", fiber.Map{
-			"Shouts": shouts,
-			"UserID": nil,
+			"Shouts":    shouts,
+			"Mentioned": mentioned,
+			"Page":      page,
+			"Trending":  trendingTags(),
+			"UserID":    nil,
 		}, "layouts/main")
 	}
 
@@ -179,11 +194,68 @@ func GetGlobalFeed(c *fiber.Ctx) error {
 
 	return c.Render("echo_chamber", fiber.Map{
 		"Shouts":            shouts,
+		"Mentioned":         mentioned,
+		"Page":              page,
+		"Trending":          trendingTags(),
 		"UserID":            uid,
 		"NotificationCount": count,
 	}, "layouts/main")
 }
 
+// RequireWebSocket rejects requests to WebSocket routes that are not upgrade requests.
+func RequireWebSocket(c *fiber.Ctx) error {
+	if websocket.IsWebSocketUpgrade(c) {
+		return c.Next()
+	}
+	return fiber.ErrUpgradeRequired
+}
+
+// StreamGlobalFeed pushes newly created shouts and echoes to a live echo chamber connection as JSON.
+// Items by authors the viewer has blocked, been blocked by or muted are not sent. The connection is
+// pinged periodically and closed if it stops answering or falls too far behind.
+func StreamGlobalFeed(conn *websocket.Conn) {
+	viewerID, _ := conn.Locals("UserID").(uint)
+	client := feed.DefaultHub.Register(viewerID)
+	defer feed.DefaultHub.Unregister(client)
+
+	// The client never sends anything meaningful, but reading is required to process pongs and close frames.
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(feedPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(feedPongWait))
+	})
+	go func() {
+		defer feed.DefaultHub.Unregister(client)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(feedPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-client.Send():
+			conn.SetWriteDeadline(time.Now().Add(feedWriteWait))
+			if !ok {
+				// Unregistered: either the reader saw the socket close or the client fell behind.
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
 // GetGlobalShout retrieves a single global shout.
 func GetGlobalShout(c *fiber.Ctx) error {
 	// Try to get the user ID; if there's an error or uid is zero, treat the user as anonymous.
@@ -191,20 +263,27 @@ func GetGlobalShout(c *fiber.Ctx) error {
 
 	// Get the shout ID from the URL.
 	id := c.Params("id")
+	// Shouts and echoes by suspended, banned or shadowbanned users are hidden from everyone else.
+	hidden := models.HiddenAuthorIDs(db.DB, uid)
 	var shout models.Shout
-	result := db.DB.Preload("Echoes").Preload("User").First(&shout, id)
+	result := db.DB.Preload("Echoes", "user_id NOT IN (?)", hidden).Preload("Echoes.User").Preload("User").
+		Where("user_id NOT IN (?)", hidden).First(&shout, id)
 	if result.Error != nil {
 		return c.SendStatus(404)
 	}
 
-	// If a valid user is logged in, fetch notification count.
+	// If a valid user is logged in, fetch notification count and the user, whose permissions decide
+	// which moderation controls are shown.
 	if uid != 0 {
+		actor, _ := currentUser(c)
 		var count int64
 		db.DB.Model(&models.Notification{}).
 			Where("user_id = ? AND read = ?", uid, false).
 			Count(&count)
 		return c.Render("global_shout", fiber.Map{
 			"Shout":             shout,
+			"Mentioned":         mentionedUsernames(shout.ID),
+			"Actor":             actor,
 			"UserID":            uid,
 			"NotificationCount": count,
 		}, "layouts/main")
@@ -212,8 +291,10 @@ func GetGlobalShout(c *fiber.Ctx) error {
 
 	// Otherwise, render without user-specific data.
 	return c.Render("global_shout", fiber.Map{
-		"Shout":  shout,
-		"UserID": nil,
+		"Shout":     shout,
+		"Mentioned": mentionedUsernames(shout.ID),
+		"Actor":     models.User{},
+		"UserID":    nil,
 	}, "layouts/main")
 }
 
@@ -225,9 +306,10 @@ func CreateGlobalEcho(c *fiber.Ctx) error {
 		return c.Redirect("/login")
 	}
 
+	// Hidden authors include users on either side of a block, so blocked users cannot echo each other.
 	id := c.Params("id")
 	var shout models.Shout
-	result := db.DB.First(&shout, id)
+	result := db.DB.Where("user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, uid)).First(&shout, id)
 	if result.Error != nil {
 		return c.SendStatus(404)
 	}
@@ -236,11 +318,26 @@ func CreateGlobalEcho(c *fiber.Ctx) error {
 	if content == "" {
 		return c.Redirect("/global/shout/" + id)
 	}
-	echo := models.Echo{Content: content, ShoutID: shout.ID}
-	db.DB.Create(&echo)
+	echo := models.Echo{Content: content, ShoutID: shout.ID, UserID: uid}
+	if err := echo.Create(db.DB); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	publishEchoEvent(&echo, shout)
+
 	return c.Redirect("/global/shout/" + id)
 }
 
+// publishEchoEvent loads the echo's author and publishes an EchoCreatedEvent for it.
+// Failures are logged rather than returned, since the echo itself has already been saved.
+func publishEchoEvent(echo *models.Echo, shout models.Shout) {
+	if err := db.DB.First(&echo.User, echo.UserID).Error; err != nil {
+		log.Printf("Failed to load echo author: %v", err)
+	}
+	if err := events.PublishEchoEvent(echo.ToEvent(shout)); err != nil {
+		log.Printf("Failed to publish echo event for echo %d: %v", echo.ID, err)
+	}
+}
+
 // EditShoutForm renders the edit form for a given shout.
 func EditShoutForm(c *fiber.Ctx) error {
 	uid := c.Locals("UserID").(uint)
@@ -251,8 +348,7 @@ func EditShoutForm(c *fiber.Ctx) error {
 		return c.Status(404).SendString("Shout not found")
 	}
 
-	// Ensure that the shout belongs to the logged-in user.
-	if shout.UserID != uid {
+	if !can(c, policy.EditShout, shout) {
 		return c.Status(403).SendString("Unauthorized")
 	}
 
@@ -268,15 +364,13 @@ func EditShoutForm(c *fiber.Ctx) error {
 
 // UpdateShout handles the update request.
 func UpdateShout(c *fiber.Ctx) error {
-	uid := c.Locals("UserID").(uint)
-
 	shoutID := c.Params("id")
 	var shout models.Shout
 	if err := db.DB.First(&shout, shoutID).Error; err != nil {
 		return c.Status(404).SendString("Shout not found")
 	}
 
-	if shout.UserID != uid {
+	if !can(c, policy.EditShout, shout) {
 		return c.Status(403).SendString("Unauthorized")
 	}
 
@@ -298,14 +392,82 @@ func DeleteShout(c *fiber.Ctx) error {
 		return c.Status(404).SendString("Shout not found")
 	}
 
-	// Ensure the shout belongs to the logged-in user
-	if shout.UserID != uid {
+	// Authors can delete their own shouts, and moderators anyone's.
+	if !can(c, policy.DeleteShout, shout) {
 		return c.Status(403).SendString("Unauthorized")
 	}
 
-	if err := db.DB.Delete(&shout).Error; err != nil {
+	if err := shout.Delete(db.DB); err != nil {
 		return c.Status(500).SendString("Failed to delete shout")
 	}
 
+	if shout.UserID != uid {
+		return c.Redirect("/echo-chamber")
+	}
 	return c.Redirect("/")
 }
+
+// DeleteEcho handles deleting an echo, which its author or a moderator may do.
+func DeleteEcho(c *fiber.Ctx) error {
+	var echo models.Echo
+	if err := db.DB.First(&echo, c.Params("id")).Error; err != nil {
+		return c.Status(404).SendString("Echo not found")
+	}
+
+	if !can(c, policy.DeleteEcho, echo) {
+		return c.Status(403).SendString("Unauthorized")
+	}
+
+	if err := echo.Delete(db.DB); err != nil {
+		return c.Status(500).SendString("Failed to delete echo")
+	}
+
+	return c.Redirect(fmt.Sprintf("/global/shout/%d", echo.ShoutID))
+}
+
+// withoutMuted leaves shouts by users the logged-in user has muted out of a feed query. Mutes only
+// apply to feeds: a muted user's profile and shouts can still be visited directly.
+func withoutMuted(c *fiber.Ctx, query *gorm.DB) *gorm.DB {
+	return query.Where("shouts.user_id NOT IN (?)", models.MutedUserIDs(db.DB, c.Locals("UserID").(uint)))
+}
+
+// findShoutPage loads the page of shouts selected by the request's cursor parameters, newest first,
+// and fills in their echo counts. Shouts the logged-in user may not see, such as those of suspended
+// or blocked users, are left out. Errors are returned as HTTP errors ready to be returned by a handler.
+func findShoutPage(c *fiber.Ctx, query *gorm.DB) ([]models.Shout, pagination.Page, error) {
+	req, err := pagination.FromRequest(c)
+	if err != nil {
+		return nil, pagination.Page{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	query = query.Where("shouts.user_id NOT IN (?)", models.HiddenAuthorIDs(db.DB, c.Locals("UserID").(uint)))
+
+	shouts, page, err := pagination.Find(query, req, models.Shout.Cursor)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		return nil, page, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	if err := models.LoadEchoCounts(db.DB, shouts); err != nil {
+		log.Printf("Error loading echo counts: %v", err)
+	}
+	return shouts, page, nil
+}
+
+// mentionedUsernames loads the usernames @mentioned in the shouts with shoutIDs or in their echoes,
+// for the renderContent template helper to link. If that fails, mentions are shown as plain text.
+func mentionedUsernames(shoutIDs ...uint) map[string]bool {
+	mentioned, err := models.MentionedUsernames(db.DB, shoutIDs)
+	if err != nil {
+		log.Printf("Error loading mentioned users: %v", err)
+	}
+	return mentioned
+}
+
+// shoutIDs returns the IDs of shouts, in order.
+func shoutIDs(shouts []models.Shout) []uint {
+	ids := make([]uint, len(shouts))
+	for i, shout := range shouts {
+		ids[i] = shout.ID
+	}
+	return ids
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+func TestShoutPageLinksMentionedUsers(t *testing.T) {
+	app := newTestApp(t)
+	alice := createUser(t, "alice", "alice@example.com")
+	createUser(t, "bob", "bob@example.com")
+	shout := models.Shout{UserID: alice.ID, Content: "hi @bob and @nobody #void"}
+	if err := shout.Create(db.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	body := readBody(t, newTestClient(t, app).get(fmt.Sprintf("/global/shout/%d", shout.ID)))
+	for _, want := range []string{
+		`<a href="/users/bob" class="mention">@bob</a>`,
+		` @nobody `,
+		`<a href="/tags/void" class="hashtag">#void</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("shout page does not contain %q", want)
+		}
+	}
+}
+
+func TestFeedsRenderContentLinks(t *testing.T) {
+	app := newTestApp(t)
+	alice := createUser(t, "alice", "alice@example.com")
+	createUser(t, "bob", "bob@example.com")
+	shout := models.Shout{UserID: alice.ID, Content: "hi @bob #void"}
+	if err := shout.Create(db.DB); err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, app)
+	client.login("alice@example.com")
+
+	for _, path := range []string{"/", "/echo-chamber", "/tags/void", "/users/alice"} {
+		body := readBody(t, client.get(path))
+		for _, want := range []string{
+			`<a href="/users/bob" class="mention">@bob</a>`,
+			`<a href="/tags/void" class="hashtag">#void</a>`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s does not contain %q", path, want)
+			}
+		}
+	}
+}
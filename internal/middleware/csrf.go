@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/csrf"
+
+	"Void/pkg/session"
+)
+
+const (
+	// CSRFContextKey is the local, and so the template variable, holding the request's CSRF token.
+	CSRFContextKey = "csrf"
+
+	// CSRFFormField is the form field forms submit the CSRF token in.
+	CSRFFormField = "_csrf"
+)
+
+// CSRF protects every state-changing request with a synchronizer token stored in the session.
+// Forms submit the token in the _csrf field (see the csrfField template helper) and scripts in the
+// X-Csrf-Token header. API requests carrying a Bearer access token skip the check: the token is
+// not something a browser attaches on its own, and GetUserFromToken rejects it if invalid. Other
+// Authorization headers, such as Basic credentials a browser resends to a site behind an HTTP-auth
+// proxy, do not. It must be installed after session.InitStore and before any routes.
+func CSRF() fiber.Handler {
+	return csrf.New(csrf.Config{
+		Session:        session.Store,
+		ContextKey:     CSRFContextKey,
+		CookieName:     "csrf_",
+		CookieSecure:   session.SecureCookies(),
+		CookieSameSite: "Lax",
+		CookieHTTPOnly: true,
+		Expiration:     24 * time.Hour,
+		Extractor:      csrfToken,
+		Next: func(c *fiber.Ctx) bool {
+			scheme, _, _ := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+			return strings.HasPrefix(c.Path(), "/api/") && strings.EqualFold(scheme, "Bearer")
+		},
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if strings.HasPrefix(c.Path(), "/api/") {
+				return jsonError(c, fiber.StatusForbidden, "invalid or missing CSRF token")
+			}
+			return c.Status(fiber.StatusForbidden).SendString("Invalid or missing CSRF token. Please go back, reload the page and try again.")
+		},
+	})
+}
+
+// csrfToken reads the token from the X-Csrf-Token header, or else from the _csrf form field.
+func csrfToken(c *fiber.Ctx) (string, error) {
+	if token := c.Get(csrf.HeaderName); token != "" {
+		return token, nil
+	}
+	return csrf.CsrfFromForm(CSRFFormField)(c)
+}
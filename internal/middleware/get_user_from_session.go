@@ -1,13 +1,45 @@
 package middleware
 
 import (
+	"log"
+
+	"Void/internal/db"
+	"Void/internal/models"
 	"Void/pkg/session"
 	"github.com/gofiber/fiber/v2"
 )
 
+// GetUserFromSession populates the "UserID" local from the session, or zero if nobody is logged in,
+// and the "IsAdmin" and "IsModerator" locals that decide whether the navigation links to the admin
+// pages and the moderation queue. A session is destroyed if it predates the user's last session
+// version change (e.g. a password reset), if the user is suspended or banned, or if the user revoked
+// it from the active sessions page; otherwise its last-seen time is updated.
 func GetUserFromSession(c *fiber.Ctx) error {
 	uid, _ := session.GetUserID(c)
+	isAdmin, isModerator := false, false
+
+	if uid != 0 {
+		version, _ := session.GetSessionVersion(c)
+		sessionID, _ := session.ID(c)
+		var user models.User
+		if err := db.DB.Select("id", "session_version", "role", "status", "suspended_until").First(&user, uid).Error; err != nil ||
+			user.SessionVersion != version || user.Restricted() {
+			models.DeleteUserSession(db.DB, sessionID)
+			session.DestroySession(c)
+			uid = 0
+		} else if record, err := models.FindUserSession(db.DB, sessionID); err != nil || record.UserID != uid {
+			session.DestroySession(c)
+			uid = 0
+		} else {
+			isAdmin, isModerator = user.HasRole(models.RoleAdmin), user.HasRole(models.RoleModerator)
+			if err := record.Touch(db.DB, c.IP()); err != nil {
+				log.Printf("Error updating session %d: %v", record.ID, err)
+			}
+		}
+	}
 
 	c.Locals("UserID", uid)
+	c.Locals("IsAdmin", isAdmin)
+	c.Locals("IsModerator", isModerator)
 	return c.Next()
 }
@@ -12,3 +12,12 @@ func RequireLogin(c *fiber.Ctx) error {
 	}
 	return c.Next()
 }
+
+// RequireAPILogin checks if the user is logged in, responding with a JSON 401 error instead of redirecting.
+func RequireAPILogin(c *fiber.Ctx) error {
+	uid := c.Locals("UserID").(uint)
+	if uid == 0 {
+		return jsonError(c, fiber.StatusUnauthorized, "authentication required")
+	}
+	return c.Next()
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// RequireRole allows the request only if the logged-in user has role or a more privileged one,
+// responding with 403 otherwise. It must run after RequireLogin.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var user models.User
+		if err := db.DB.Select("role").First(&user, c.Locals("UserID").(uint)).Error; err != nil || !user.HasRole(role) {
+			return c.Status(fiber.StatusForbidden).SendString("You don't have permission to do that")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// RequireVerified allows the request only if the logged-in user has verified their email address,
+// redirecting anyone else to the verification page. It must run after RequireLogin.
+func RequireVerified(c *fiber.Ctx) error {
+	if !isVerified(c.Locals("UserID").(uint)) {
+		return c.Redirect("/verify-email")
+	}
+	return c.Next()
+}
+
+// RequireAPIVerified is RequireVerified for the JSON API, responding with a JSON 403 error instead of redirecting.
+func RequireAPIVerified(c *fiber.Ctx) error {
+	if !isVerified(c.Locals("UserID").(uint)) {
+		return jsonError(c, fiber.StatusForbidden, "verify your email address before posting")
+	}
+	return c.Next()
+}
+
+// isVerified reports whether userID has verified their email address.
+func isVerified(userID uint) bool {
+	var user models.User
+	if err := db.DB.Select("verified").First(&user, userID).Error; err != nil {
+		return false
+	}
+	return user.Verified
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Access token scopes.
+const (
+	ScopeRead          = "read"          // Read shouts, echoes and profiles
+	ScopeWrite         = "write"         // Create, edit and delete shouts and echoes
+	ScopeNotifications = "notifications" // Read notifications and mark them as read
+)
+
+// AllScopes lists every scope a token may be granted, in display order.
+var AllScopes = []string{ScopeRead, ScopeWrite, ScopeNotifications}
+
+// AccessTokenTouchInterval is how often a token's LastUsedAt is updated while it is in use.
+const AccessTokenTouchInterval = time.Minute
+
+// accessTokenPrefix marks Void personal access tokens so they are easy to recognize, e.g. in secret scanners.
+const accessTokenPrefix = "void_"
+
+// AccessToken is a named personal access token a user can create for API and script authentication.
+// Only a SHA-256 hash of the token is stored; the plaintext is shown once when it is created.
+// Revoking a token soft-deletes it.
+type AccessToken struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	TokenHash  string `gorm:"uniqueIndex;not null"`
+	Hint       string `gorm:"not null"` // The first few characters of the token, to help users tell tokens apart
+	Scopes     string `gorm:"not null"` // Comma-separated list of granted scopes
+	LastUsedAt *time.Time
+}
+
+// NewAccessToken generates a token for userID with the given name and scopes, returning the
+// unsaved model and the plaintext token. The plaintext cannot be recovered later.
+func NewAccessToken(userID uint, name string, scopes []string) (*AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &ValidationError{Message: "token name cannot be empty"}
+	}
+	if len(scopes) == 0 {
+		return nil, "", &ValidationError{Message: "select at least one scope"}
+	}
+	var granted []string
+	for _, scope := range AllScopes {
+		if slices.Contains(scopes, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) != len(scopes) {
+		return nil, "", &ValidationError{Message: "unknown token scope"}
+	}
+
+	plaintext, err := generateToken(accessTokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(plaintext),
+		Hint:      plaintext[:len(accessTokenPrefix)+4],
+		Scopes:    strings.Join(granted, ","),
+	}
+	return token, plaintext, nil
+}
+
+// FindAccessToken looks up the unrevoked token matching plaintext.
+func FindAccessToken(db *gorm.DB, plaintext string) (*AccessToken, error) {
+	var token AccessToken
+	if err := db.Where("token_hash = ?", hashToken(plaintext)).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ScopeList returns the token's granted scopes.
+func (t AccessToken) ScopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope reports whether the token was granted scope.
+func (t AccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.ScopeList(), scope)
+}
+
+// Touch records that the token was just used. Writes are throttled to one per AccessTokenTouchInterval.
+func (t *AccessToken) Touch(db *gorm.DB) error {
+	if t.LastUsedAt != nil && time.Since(*t.LastUsedAt) < AccessTokenTouchInterval {
+		return nil
+	}
+	now := time.Now()
+	t.LastUsedAt = &now
+	return db.Model(t).UpdateColumn("last_used_at", now).Error
+}
+
+// RevokeAccessToken revokes one of userID's tokens. Revoking a token that does not exist,
+// or belongs to someone else, is reported as gorm.ErrRecordNotFound.
+func RevokeAccessToken(db *gorm.DB, userID, tokenID uint) error {
+	result := db.Where("user_id = ?", userID).Delete(&AccessToken{}, tokenID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"Void/internal/models"
+)
+
+func TestAccessTokenTouchIsThrottled(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+	token, plaintext, err := models.NewAccessToken(user.ID, "script", []string{models.ScopeRead})
+	if err != nil {
+		t.Fatalf("NewAccessToken: %v", err)
+	}
+	if err := conn.Create(token).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	lastUsed := func() time.Time {
+		t.Helper()
+		found, err := models.FindAccessToken(conn, plaintext)
+		if err != nil {
+			t.Fatalf("FindAccessToken: %v", err)
+		}
+		if found.LastUsedAt == nil {
+			t.Fatal("LastUsedAt was not set")
+		}
+		return *found.LastUsedAt
+	}
+
+	if err := token.Touch(conn); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	first := lastUsed()
+
+	// Used again within the interval: nothing is written.
+	found, _ := models.FindAccessToken(conn, plaintext)
+	if err := found.Touch(conn); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if got := lastUsed(); !got.Equal(first) {
+		t.Errorf("LastUsedAt changed to %v within the touch interval, want %v", got, first)
+	}
+
+	// Used again after the interval: the write goes through.
+	stale := time.Now().Add(-models.AccessTokenTouchInterval - time.Second)
+	conn.Model(&models.AccessToken{}).Where("id = ?", token.ID).UpdateColumn("last_used_at", stale)
+	found, _ = models.FindAccessToken(conn, plaintext)
+	if err := found.Touch(conn); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if got := lastUsed(); !got.After(stale.Add(time.Second)) {
+		t.Errorf("LastUsedAt = %v after the touch interval, want it updated", got)
+	}
+}
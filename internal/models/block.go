@@ -0,0 +1,140 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxMute is the longest a time-limited mute may last.
+const MaxMute = 365 * 24 * time.Hour
+
+// Block stops two users from seeing or interacting with each other. It works both ways, whoever
+// created it.
+type Block struct {
+	gorm.Model
+	BlockerID uint `gorm:"not null;uniqueIndex:idx_blocker_blocked"` // The user who blocked
+	BlockedID uint `gorm:"not null;uniqueIndex:idx_blocker_blocked;index"`
+	Blocked   User `gorm:"foreignKey:BlockedID"`
+}
+
+// Mute hides a user's shouts from the muter's feeds, without the muted user knowing.
+type Mute struct {
+	gorm.Model
+	MuterID   uint       `gorm:"not null;uniqueIndex:idx_muter_muted"` // The user who muted
+	MutedID   uint       `gorm:"not null;uniqueIndex:idx_muter_muted"`
+	ExpiresAt *time.Time // When the mute ends; nil for a mute that lasts until it is removed
+	Muted     User       `gorm:"foreignKey:MutedID"`
+}
+
+// BlockUser blocks blockedID for blockerID, and removes any follows between the two. Blocking
+// someone twice is a no-op.
+func BlockUser(db *gorm.DB, blockerID, blockedID uint) error {
+	if blockerID == blockedID {
+		return &ValidationError{Message: "you can't block yourself"}
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		block := Block{BlockerID: blockerID, BlockedID: blockedID}
+		if err := tx.Where(block).FirstOrCreate(&block).Error; err != nil {
+			return err
+		}
+		if err := Unfollow(tx, blockerID, blockedID); err != nil {
+			return err
+		}
+		return Unfollow(tx, blockedID, blockerID)
+	})
+}
+
+// UnblockUser removes blockerID's block of blockedID, if any. The row is hard-deleted so that a
+// later block does not collide with the unique index.
+func UnblockUser(db *gorm.DB, blockerID, blockedID uint) error {
+	return db.Unscoped().Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&Block{}).Error
+}
+
+// IsBlocking reports whether blockerID has blocked blockedID.
+func IsBlocking(db *gorm.DB, blockerID, blockedID uint) bool {
+	var count int64
+	db.Model(&Block{}).Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Count(&count)
+	return count > 0
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func IsBlocked(db *gorm.DB, userID, otherID uint) bool {
+	var count int64
+	db.Model(&Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// BlockedUserIDs returns a subquery selecting the IDs of every user userID has blocked or been
+// blocked by.
+func BlockedUserIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&Block{}).
+		Select("CASE WHEN blocker_id = ? THEN blocked_id ELSE blocker_id END", userID).
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID)
+}
+
+// UserBlocks returns the users blockerID has blocked, most recent first.
+func UserBlocks(db *gorm.DB, blockerID uint) ([]Block, error) {
+	var blocks []Block
+	err := db.Preload("Blocked").Where("blocker_id = ?", blockerID).Order("created_at DESC").Find(&blocks).Error
+	return blocks, err
+}
+
+// MuteUser mutes mutedID for muterID until expiresAt, or until unmuted if expiresAt is nil.
+// Muting someone who is already muted changes when the mute ends.
+func MuteUser(db *gorm.DB, muterID, mutedID uint, expiresAt *time.Time) error {
+	if muterID == mutedID {
+		return &ValidationError{Message: "you can't mute yourself"}
+	}
+	if expiresAt != nil && (!expiresAt.After(time.Now()) || expiresAt.After(time.Now().Add(MaxMute))) {
+		return &ValidationError{Message: "a mute must end in the future and last at most a year"}
+	}
+	mute := Mute{MuterID: muterID, MutedID: mutedID}
+	return db.Where(mute).Assign(map[string]interface{}{"expires_at": expiresAt}).FirstOrCreate(&mute).Error
+}
+
+// UnmuteUser removes muterID's mute of mutedID, if any. The row is hard-deleted so that a later
+// mute does not collide with the unique index.
+func UnmuteUser(db *gorm.DB, muterID, mutedID uint) error {
+	return db.Unscoped().Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Delete(&Mute{}).Error
+}
+
+// activeMutes restricts a query on mutes to those that have not expired.
+func activeMutes(db *gorm.DB) *gorm.DB {
+	return db.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+// IsMuting reports whether muterID currently mutes mutedID.
+func IsMuting(db *gorm.DB, muterID, mutedID uint) bool {
+	var count int64
+	db.Model(&Mute{}).Scopes(activeMutes).Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Count(&count)
+	return count > 0
+}
+
+// MutedUserIDs returns a subquery selecting the IDs of every user muterID currently mutes.
+func MutedUserIDs(db *gorm.DB, muterID uint) *gorm.DB {
+	return db.Model(&Mute{}).Scopes(activeMutes).Select("muted_id").Where("muter_id = ?", muterID)
+}
+
+// UsersHidingAuthor returns the IDs of every user who must not see authorID's shouts: those on
+// either side of a block with them, and those currently muting them. It is the inverse of
+// filtering a viewer's feed by BlockedUserIDs and MutedUserIDs, for pushing one shout to many viewers.
+func UsersHidingAuthor(db *gorm.DB, authorID uint) ([]uint, error) {
+	var blocked, muters []uint
+	if err := BlockedUserIDs(db, authorID).Scan(&blocked).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&Mute{}).Scopes(activeMutes).Where("muted_id = ?", authorID).Pluck("muter_id", &muters).Error; err != nil {
+		return nil, err
+	}
+	return append(blocked, muters...), nil
+}
+
+// UserMutes returns the users muterID currently mutes, most recent first.
+func UserMutes(db *gorm.DB, muterID uint) ([]Mute, error) {
+	var mutes []Mute
+	err := db.Preload("Muted").Scopes(activeMutes).Where("muter_id = ?", muterID).Order("created_at DESC").Find(&mutes).Error
+	return mutes, err
+}
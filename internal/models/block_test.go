@@ -0,0 +1,45 @@
+package models_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"Void/internal/models"
+)
+
+func TestUsersHidingAuthor(t *testing.T) {
+	conn := newTestDB(t)
+	author := createUser(t, conn, "author", "author@example.com")
+	blocker := createUser(t, conn, "blocker", "blocker@example.com")
+	blocked := createUser(t, conn, "blocked", "blocked@example.com")
+	muter := createUser(t, conn, "muter", "muter@example.com")
+	lapsed := createUser(t, conn, "lapsed", "lapsed@example.com")
+	createUser(t, conn, "bystander", "bystander@example.com")
+
+	if err := models.BlockUser(conn, blocker.ID, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.BlockUser(conn, author.ID, blocked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.MuteUser(conn, muter.ID, author.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	ends := time.Now().Add(time.Hour)
+	if err := models.MuteUser(conn, lapsed.ID, author.ID, &ends); err != nil {
+		t.Fatal(err)
+	}
+	conn.Model(&models.Mute{}).Where("muter_id = ?", lapsed.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	got, err := models.UsersHidingAuthor(conn, author.ID)
+	if err != nil {
+		t.Fatalf("UsersHidingAuthor: %v", err)
+	}
+	slices.Sort(got)
+	want := []uint{blocker.ID, blocked.ID, muter.ID}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("UsersHidingAuthor = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MentionPattern matches @username tokens that are not part of a larger word, such as an email address.
+// The first group captures the preceding character, if any, and the second the username.
+var MentionPattern = regexp.MustCompile(`(^|[^\w@])@(\w+)`)
+
+// HashtagPattern matches #tag tokens that are not part of a larger word or an HTML entity such as &#39;.
+// The first group captures the preceding character, if any, and the second the tag name.
+var HashtagPattern = regexp.MustCompile(`(^|[^\w&#])#(\w+)`)
+
+// ParseMentions returns the unique usernames mentioned in content, in the order they first appear.
+func ParseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range MentionPattern.FindAllStringSubmatch(content, -1) {
+		key := strings.ToLower(match[2])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, match[2])
+	}
+	return usernames
+}
+
+// ParseHashtags returns the unique, lower-cased tag names in content, in the order they first appear.
+func ParseHashtags(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range HashtagPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(match[2])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"net/url"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// newTestDB returns a fresh, fully migrated in-memory database that is closed when the test ends.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := db.Open("file:" + url.PathEscape(t.Name()) + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// createUser stores a verified user with the given username and email.
+func createUser(t *testing.T, conn *gorm.DB, username, email string) models.User {
+	t.Helper()
+	user := models.User{Username: username, Email: email, Password: "hash", Verified: true}
+	if err := user.Create(conn); err != nil {
+		t.Fatalf("creating user %s: %v", username, err)
+	}
+	return user
+}
@@ -1,7 +1,8 @@
 package models
 
 import (
-	"errors"
+	"Void/internal/pagination"
+
 	"gorm.io/gorm"
 )
 
@@ -10,13 +11,91 @@ type Echo struct {
 	gorm.Model
 	Content string `gorm:"not null"`
 	ShoutID uint   `gorm:"not null"`
+	UserID  uint   // The author of the echo (zero for echoes created before authors were tracked)
+	User    User   // Association to the user.
+}
+
+// EchoCreatedEvent is the event payload for when an echo is created.
+type EchoCreatedEvent struct {
+	EchoID        uint   `json:"echo_id"`
+	ShoutID       uint   `json:"shout_id"`
+	ShoutAuthorID uint   `json:"shout_author_id"`
+	Content       string `json:"content"`
+	UserID        uint   `json:"user_id"`
+	Username      string `json:"username"`
+	Avatar        string `json:"avatar"`
 }
 
-// Create persists the echo using the provided DB instance.
+// Create persists the echo and any @mentions it contains using the provided DB instance.
 // It also performs a simple validation to ensure content is not empty.
 func (e *Echo) Create(db *gorm.DB) error {
 	if e.Content == "" {
-		return errors.New("echo content cannot be empty")
+		return &ValidationError{Message: "echo content cannot be empty"}
 	}
-	return db.Create(e).Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		return createMentions(tx, e.Content, e.UserID, e.ShoutID, e.ID)
+	})
+}
+
+// Delete soft-deletes the echo.
+func (e *Echo) Delete(db *gorm.DB) error {
+	return db.Delete(e).Error
+}
+
+// Cursor returns the echo's position for keyset pagination.
+func (e Echo) Cursor() pagination.Cursor {
+	return pagination.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+}
+
+// ToEvent converts an Echo on the given shout to an EchoCreatedEvent.
+func (e *Echo) ToEvent(shout Shout) EchoCreatedEvent {
+	return EchoCreatedEvent{
+		EchoID:        e.ID,
+		ShoutID:       shout.ID,
+		ShoutAuthorID: shout.UserID,
+		Content:       e.Content,
+		UserID:        e.UserID,
+		Username:      e.User.Username,
+		Avatar:        e.User.Avatar, // Make sure the User is loaded!
+	}
+}
+
+// The following methods implement the EchoEvent interface defined in the events package.
+
+// GetEchoID returns the unique identifier of the echo associated with this event.
+func (e EchoCreatedEvent) GetEchoID() uint {
+	return e.EchoID
+}
+
+// GetShoutID returns the unique identifier of the shout that was echoed.
+func (e EchoCreatedEvent) GetShoutID() uint {
+	return e.ShoutID
+}
+
+// GetShoutAuthorID returns the unique identifier of the user who wrote the echoed shout.
+func (e EchoCreatedEvent) GetShoutAuthorID() uint {
+	return e.ShoutAuthorID
+}
+
+// GetContent retrieves the content of the echo.
+func (e EchoCreatedEvent) GetContent() string {
+	return e.Content
+}
+
+// GetUserID retrieves the unique identifier of the user who wrote the echo.
+func (e EchoCreatedEvent) GetUserID() uint {
+	return e.UserID
+}
+
+// GetUsername retrieves the username of the user who wrote the echo.
+func (e EchoCreatedEvent) GetUsername() string {
+	return e.Username
+}
+
+// GetAvatar retrieves the avatar of the user who wrote the echo.
+func (e EchoCreatedEvent) GetAvatar() string {
+	return e.Avatar
 }
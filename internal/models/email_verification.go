@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Void/pkg/signer"
+)
+
+const (
+	// EmailVerificationTTL is how long an email verification link stays valid.
+	EmailVerificationTTL = 48 * time.Hour
+
+	// VerificationResendInterval is the minimum time between verification emails to one user.
+	VerificationResendInterval = time.Minute
+
+	// emailVerificationPurpose scopes signed verification tokens.
+	emailVerificationPurpose = "email-verification"
+)
+
+// ErrInvalidVerificationToken is returned for verification tokens that are forged, expired,
+// or issued for an email address the user no longer has.
+var ErrInvalidVerificationToken = errors.New("this verification link is invalid or has expired")
+
+// NewEmailVerificationToken returns a signed token confirming that u owns their current email address.
+func NewEmailVerificationToken(u User) string {
+	return signer.Sign(emailVerificationPurpose, strconv.FormatUint(uint64(u.ID), 10)+":"+u.Email, EmailVerificationTTL)
+}
+
+// VerifyEmail marks the user a verification token was issued to as verified and returns them.
+func VerifyEmail(db *gorm.DB, token string) (*User, error) {
+	payload, err := signer.Verify(emailVerificationPurpose, token)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	id, email, _ := strings.Cut(payload, ":")
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+	if user.Email != email {
+		return nil, ErrInvalidVerificationToken
+	}
+	if !user.Verified {
+		if err := db.Model(&user).Update("verified", true).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// MarkVerificationSent records that a verification email is being sent to u. It returns a
+// ValidationError if the previous one was sent less than VerificationResendInterval ago.
+func (u *User) MarkVerificationSent(db *gorm.DB) error {
+	if u.VerificationSentAt != nil && time.Since(*u.VerificationSentAt) < VerificationResendInterval {
+		return &ValidationError{Message: "a verification email was just sent; please wait a minute before asking for another"}
+	}
+	now := time.Now()
+	u.VerificationSentAt = &now
+	return db.Model(u).UpdateColumn("verification_sent_at", now).Error
+}
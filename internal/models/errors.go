@@ -0,0 +1,19 @@
+package models
+
+import "errors"
+
+// ValidationError reports that a model rejected its input, as opposed to a database failure.
+type ValidationError struct {
+	Message string
+}
+
+// Error returns the validation message, suitable for showing to the user.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// IsValidationError reports whether err is, or wraps, a ValidationError.
+func IsValidationError(err error) bool {
+	var v *ValidationError
+	return errors.As(err, &v)
+}
@@ -0,0 +1,81 @@
+package models
+
+import "gorm.io/gorm"
+
+// Follow represents a follower/followee relationship between two users.
+type Follow struct {
+	gorm.Model
+	FollowerID uint `gorm:"not null;uniqueIndex:idx_follower_followee"` // The user doing the following
+	FolloweeID uint `gorm:"not null;uniqueIndex:idx_follower_followee"` // The user being followed
+	Notify     bool `gorm:"default:false"`                              // Notify the follower of every post from the followee
+	Follower   User `gorm:"foreignKey:FollowerID"`
+	Followee   User `gorm:"foreignKey:FolloweeID"`
+}
+
+// Create persists the follow using the provided DB instance.
+// Users cannot follow themselves or someone they have a block with, and following someone twice
+// is a no-op.
+func (f *Follow) Create(db *gorm.DB) error {
+	if f.FollowerID == f.FolloweeID {
+		return &ValidationError{Message: "users cannot follow themselves"}
+	}
+	if IsBlocked(db, f.FollowerID, f.FolloweeID) {
+		return &ValidationError{Message: "you can't follow this user"}
+	}
+	if IsFollowing(db, f.FollowerID, f.FolloweeID) {
+		return nil
+	}
+	return db.Create(f).Error
+}
+
+// Unfollow removes the follow relationship between the two users, if any.
+// The row is hard-deleted so that a later follow does not collide with the unique index.
+func Unfollow(db *gorm.DB, followerID, followeeID uint) error {
+	return db.Unscoped().
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&Follow{}).Error
+}
+
+// IsFollowing reports whether followerID currently follows followeeID.
+func IsFollowing(db *gorm.DB, followerID, followeeID uint) bool {
+	var count int64
+	db.Model(&Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&count)
+	return count > 0
+}
+
+// SetNotify toggles whether followerID is notified of every post from followeeID.
+// It returns an error if followerID does not follow followeeID.
+func SetNotify(db *gorm.DB, followerID, followeeID uint, notify bool) error {
+	result := db.Model(&Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Update("notify", notify)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &ValidationError{Message: "you must follow this user to be notified of their posts"}
+	}
+	return nil
+}
+
+// IsNotifying reports whether followerID has opted in to notifications for every post from followeeID.
+func IsNotifying(db *gorm.DB, followerID, followeeID uint) bool {
+	var count int64
+	db.Model(&Follow{}).
+		Where("follower_id = ? AND followee_id = ? AND notify = ?", followerID, followeeID, true).
+		Count(&count)
+	return count > 0
+}
+
+// FolloweeIDs returns a subquery selecting the IDs of every user followed by userID.
+func FolloweeIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", userID)
+}
+
+// NotifiedFollowerIDs returns a subquery selecting the IDs of every follower of userID
+// who has opted in to notifications for each of userID's posts.
+func NotifiedFollowerIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&Follow{}).Select("follower_id").Where("followee_id = ? AND notify = ?", userID, true)
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limits on the invites regular users can create. Admins' invites are unrestricted; see policy.CreateUnlimitedInvites.
+const (
+	MaxUserInviteUses    = 10
+	MaxUserInviteTTL     = 30 * 24 * time.Hour
+	MaxActiveUserInvites = 5
+)
+
+// ErrInvalidInvite is returned when registering with an invite code that does not exist, is used
+// up or has expired.
+var ErrInvalidInvite = &ValidationError{Message: "that invite code is invalid, used up or expired"}
+
+// Invite is a code that lets people register while the instance is invite-only, or skip the
+// approval queue. Unlike access tokens, codes are stored in plaintext: they are meant to be shared,
+// and their creator can copy them again from the invites page.
+type Invite struct {
+	ID          uint       `gorm:"primaryKey"`
+	CreatedAt   time.Time  `gorm:"not null"`
+	CreatedByID uint       `gorm:"not null;index"`
+	Code        string     `gorm:"uniqueIndex;not null"`
+	MaxUses     int        `gorm:"not null"` // Zero means unlimited
+	Uses        int        `gorm:"not null;default:0"`
+	ExpiresAt   *time.Time // Nil means the invite never expires
+}
+
+// NewInvite creates an invite from creator that can be used maxUses times within ttl. Zero means
+// no limit for either, which only unrestricted creators may choose; others are held to
+// MaxUserInviteUses, MaxUserInviteTTL and MaxActiveUserInvites.
+func NewInvite(db *gorm.DB, creator User, unrestricted bool, maxUses int, ttl time.Duration) (*Invite, error) {
+	if maxUses < 0 || ttl < 0 {
+		return nil, &ValidationError{Message: "invite limits cannot be negative"}
+	}
+	if !unrestricted {
+		if maxUses == 0 || maxUses > MaxUserInviteUses {
+			return nil, &ValidationError{Message: fmt.Sprintf("invites can be used at most %d times", MaxUserInviteUses)}
+		}
+		if ttl == 0 || ttl > MaxUserInviteTTL {
+			return nil, &ValidationError{Message: fmt.Sprintf("invites can last at most %d days", int(MaxUserInviteTTL.Hours()/24))}
+		}
+		var active int64
+		if err := activeInvites(db.Model(&Invite{}).Where("created_by_id = ?", creator.ID)).Count(&active).Error; err != nil {
+			return nil, err
+		}
+		if active >= MaxActiveUserInvites {
+			return nil, &ValidationError{Message: fmt.Sprintf("you already have %d active invites; revoke one to create another", MaxActiveUserInvites)}
+		}
+	}
+
+	code, err := generateToken("")
+	if err != nil {
+		return nil, err
+	}
+	invite := &Invite{CreatedByID: creator.ID, Code: code[:22], MaxUses: maxUses}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		invite.ExpiresAt = &expires
+	}
+	if err := db.Create(invite).Error; err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// activeInvites limits query to invites that can still be used.
+func activeInvites(query *gorm.DB) *gorm.DB {
+	return query.Where("(expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)", time.Now())
+}
+
+// Active reports whether the invite can still be used.
+func (i Invite) Active() bool {
+	return (i.ExpiresAt == nil || i.ExpiresAt.After(time.Now())) && (i.MaxUses == 0 || i.Uses < i.MaxUses)
+}
+
+// UserInvites lists the invites userID created, newest first.
+func UserInvites(db *gorm.DB, userID uint) ([]Invite, error) {
+	var invites []Invite
+	err := db.Where("created_by_id = ?", userID).Order("created_at DESC").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite expires one of userID's invites immediately. Revoking an invite that does not
+// exist, or belongs to someone else, is reported as gorm.ErrRecordNotFound.
+func RevokeInvite(db *gorm.DB, userID, inviteID uint) error {
+	result := db.Model(&Invite{}).Where("id = ? AND created_by_id = ?", inviteID, userID).Update("expires_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// redeemInvite uses code once. The use is claimed with a conditional update, so concurrent
+// registrations cannot exceed the invite's limit.
+func redeemInvite(db *gorm.DB, code string) (*Invite, error) {
+	result := activeInvites(db.Model(&Invite{}).Where("code = ?", code)).Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidInvite
+	}
+	var invite Invite
+	if err := db.Where("code = ?", code).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a login attempt failed.
+const (
+	LoginFailedPassword  = "wrong_password" // Unknown email or wrong password
+	LoginFailedTwoFactor = "wrong_2fa_code" // Correct password, wrong second factor
+	LoginFailedLockedOut = "locked_out"     // Rejected without checking, because of an earlier lockout
+)
+
+// Brute-force protection. Each email gets AccountFreeAttempts consecutive failures, and each IP
+// address IPFreeAttempts failures within LoginAttemptWindow, before logins are refused. The refusal
+// lasts LockoutBase after the latest failure and doubles with every further failure, up to LockoutMax.
+const (
+	AccountFreeAttempts = 5
+	IPFreeAttempts      = 20
+	LoginAttemptWindow  = time.Hour
+	LockoutBase         = 30 * time.Second
+	LockoutMax          = time.Hour
+)
+
+// LoginAttempt records one attempt to log in, for lockouts and for the account owner's audit log.
+// Attempts are tracked by the email typed, whether or not an account has it; UserID is zero if not.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	UserID    uint      `gorm:"index"`
+	Email     string    `gorm:"not null;index"`
+	IP        string    `gorm:"not null;index"`
+	UserAgent string
+	Success   bool   `gorm:"not null"`
+	Reason    string // One of the LoginFailed constants for failed attempts
+}
+
+// RecordLoginAttempt stores the outcome of a login attempt.
+func RecordLoginAttempt(db *gorm.DB, attempt LoginAttempt) error {
+	attempt.Email = normalizeEmail(attempt.Email)
+	return db.Create(&attempt).Error
+}
+
+// LoginLockedUntil reports when logins for email from ip will next be accepted, or the zero time
+// if they are accepted now.
+func LoginLockedUntil(db *gorm.DB, email, ip string) (time.Time, error) {
+	email = normalizeEmail(email)
+	since := time.Now().Add(-LoginAttemptWindow)
+
+	// Failures for an account only count since its last successful login.
+	var lastSuccess LoginAttempt
+	if err := db.Where("email = ? AND success = ? AND created_at > ?", email, true, since).
+		Order("created_at desc").Limit(1).Find(&lastSuccess).Error; err != nil {
+		return time.Time{}, err
+	}
+	if lastSuccess.ID != 0 {
+		since = lastSuccess.CreatedAt
+	}
+
+	account, err := lockedUntil(db.Where("email = ? AND created_at > ?", email, since), AccountFreeAttempts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	address, err := lockedUntil(db.Where("ip = ? AND created_at > ?", ip, time.Now().Add(-LoginAttemptWindow)), IPFreeAttempts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if address.After(account) {
+		return address, nil
+	}
+	return account, nil
+}
+
+// lockedUntil applies the backoff to the failed attempts matched by scope.
+func lockedUntil(scope *gorm.DB, free int) (time.Time, error) {
+	failures := scope.Model(&LoginAttempt{}).Where("success = ? AND reason <> ?", false, LoginFailedLockedOut)
+
+	var count int64
+	if err := failures.Session(&gorm.Session{}).Count(&count).Error; err != nil || count < int64(free) {
+		return time.Time{}, err
+	}
+	var latest LoginAttempt
+	if err := failures.Session(&gorm.Session{}).Order("created_at desc").Limit(1).Find(&latest).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	delay := LockoutMax
+	if excess := count - int64(free); excess < 32 {
+		delay = min(LockoutBase<<excess, LockoutMax)
+	}
+	until := latest.CreatedAt.Add(delay)
+	if until.Before(time.Now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// RecentFailedLogins returns the latest failed login attempts on userID's account, newest first.
+func RecentFailedLogins(db *gorm.DB, userID uint, limit int) ([]LoginAttempt, error) {
+	var attempts []LoginAttempt
+	err := db.Where("user_id = ? AND success = ?", userID, false).
+		Order("created_at desc").Limit(limit).Find(&attempts).Error
+	return attempts, err
+}
+
+// normalizeEmail lower-cases email so attempts with different capitalization count together.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
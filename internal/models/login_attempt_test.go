@@ -0,0 +1,126 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"Void/internal/models"
+)
+
+// lockoutEmail and lockoutIP are the email and IP address whose lockout each case checks.
+const (
+	lockoutEmail = "alice@example.com"
+	lockoutIP    = "198.51.100.1"
+)
+
+// loginAttempt is a login attempt made ago before the test's reference time.
+type loginAttempt struct {
+	email   string
+	ip      string
+	ago     time.Duration
+	success bool
+	reason  string
+}
+
+// accountFailures returns n failed logins for lockoutEmail, ago, each from a different IP address.
+func accountFailures(n int, ago time.Duration) []loginAttempt {
+	attempts := make([]loginAttempt, n)
+	for i := range attempts {
+		attempts[i] = loginAttempt{email: lockoutEmail, ip: fmt.Sprintf("192.0.2.%d", i+1), ago: ago, reason: models.LoginFailedPassword}
+	}
+	return attempts
+}
+
+// ipFailures returns n failed logins from lockoutIP, ago, each for a different email.
+func ipFailures(n int, ago time.Duration) []loginAttempt {
+	attempts := make([]loginAttempt, n)
+	for i := range attempts {
+		attempts[i] = loginAttempt{email: fmt.Sprintf("user%d@example.com", i), ip: lockoutIP, ago: ago, reason: models.LoginFailedPassword}
+	}
+	return attempts
+}
+
+func TestLoginLockedUntil(t *testing.T) {
+	concat := func(groups ...[]loginAttempt) []loginAttempt {
+		var all []loginAttempt
+		for _, g := range groups {
+			all = append(all, g...)
+		}
+		return all
+	}
+	success := []loginAttempt{{email: lockoutEmail, ip: "192.0.2.200", ago: time.Second, success: true}}
+	lockedOut := make([]loginAttempt, 10)
+	for i := range lockedOut {
+		lockedOut[i] = loginAttempt{email: lockoutEmail, ip: lockoutIP, reason: models.LoginFailedLockedOut}
+	}
+
+	tests := []struct {
+		name     string
+		attempts []loginAttempt
+		// want is how long after the reference time logins are refused; zero means they are accepted.
+		want time.Duration
+	}{
+		{"no attempts", nil, 0},
+		{"account below the free attempts", accountFailures(models.AccountFreeAttempts-1, 0), 0},
+		{"account at the free attempts", accountFailures(models.AccountFreeAttempts, 0), models.LockoutBase},
+		{"account one over", accountFailures(models.AccountFreeAttempts+1, 0), 2 * models.LockoutBase},
+		{"account three over", accountFailures(models.AccountFreeAttempts+3, 0), 8 * models.LockoutBase},
+		{"account capped at LockoutMax", accountFailures(models.AccountFreeAttempts+40, 0), models.LockoutMax},
+		{"lockout measured from the latest failure", accountFailures(models.AccountFreeAttempts+1, 10*time.Second), 2*models.LockoutBase - 10*time.Second},
+		{"lockout over", accountFailures(models.AccountFreeAttempts, time.Minute), 0},
+		{"success resets account failures", concat(accountFailures(models.AccountFreeAttempts+2, 2*time.Second), success, accountFailures(1, 0)), 0},
+		{"locked_out rows do not count", concat(accountFailures(models.AccountFreeAttempts-1, 0), lockedOut), 0},
+		{"IP below the free attempts", ipFailures(models.IPFreeAttempts-1, 0), 0},
+		{"IP at the free attempts", ipFailures(models.IPFreeAttempts, 0), models.LockoutBase},
+		{"IP one over", ipFailures(models.IPFreeAttempts+1, 0), 2 * models.LockoutBase},
+		{"IP failures outside the window", concat(ipFailures(models.IPFreeAttempts-1, models.LoginAttemptWindow+time.Minute), ipFailures(1, 0)), 0},
+		{"the longer of the account and IP lockouts", concat(accountFailures(models.AccountFreeAttempts+2, 0), ipFailures(models.IPFreeAttempts, 0)), 4 * models.LockoutBase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newTestDB(t)
+			now := time.Now()
+			for _, a := range tt.attempts {
+				err := models.RecordLoginAttempt(conn, models.LoginAttempt{
+					CreatedAt: now.Add(-a.ago),
+					Email:     a.email,
+					IP:        a.ip,
+					Success:   a.success,
+					Reason:    a.reason,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			until, err := models.LoginLockedUntil(conn, lockoutEmail, lockoutIP)
+			if err != nil {
+				t.Fatalf("LoginLockedUntil: %v", err)
+			}
+			if tt.want == 0 {
+				if !until.IsZero() {
+					t.Errorf("locked until %v, want unlocked", until)
+				}
+				return
+			}
+			if got := until.Sub(now); got < tt.want-time.Millisecond || got > tt.want+time.Millisecond {
+				t.Errorf("locked for %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLockedUntilIgnoresEmailCase(t *testing.T) {
+	conn := newTestDB(t)
+	for i := 0; i < models.AccountFreeAttempts; i++ {
+		models.RecordLoginAttempt(conn, models.LoginAttempt{Email: " Alice@Example.COM", IP: fmt.Sprintf("192.0.2.%d", i), Reason: models.LoginFailedPassword})
+	}
+	until, err := models.LoginLockedUntil(conn, lockoutEmail, lockoutIP)
+	if err != nil {
+		t.Fatalf("LoginLockedUntil: %v", err)
+	}
+	if until.IsZero() {
+		t.Error("failures typed with different capitalization did not count towards the lockout")
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import "gorm.io/gorm"
+
+// Mention records that a user was @mentioned in a shout or in an echo on that shout.
+type Mention struct {
+	gorm.Model
+	UserID  uint `gorm:"not null;index"` // The mentioned user's ID
+	ShoutID uint `gorm:"not null;index"` // The shout containing the mention, or the shout the echo belongs to
+	EchoID  uint `gorm:"index"`          // The echo containing the mention; zero when the mention is in the shout itself
+	User    User // Association to the mentioned user.
+}
+
+// createMentions parses @username tokens out of content, written by authorID, and stores a Mention
+// for every existing user referenced. Unknown usernames, and users who have blocked or been blocked
+// by the author, are ignored.
+func createMentions(db *gorm.DB, content string, authorID, shoutID, echoID uint) error {
+	usernames := ParseMentions(content)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	var users []User
+	if err := db.Where("username IN ?", usernames).
+		Where("id NOT IN (?)", BlockedUserIDs(db, authorID)).Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	mentions := make([]Mention, 0, len(users))
+	for _, user := range users {
+		mentions = append(mentions, Mention{UserID: user.ID, ShoutID: shoutID, EchoID: echoID})
+	}
+	return db.Create(&mentions).Error
+}
+
+// MentionedUsernames returns the usernames of the users mentioned in the shouts with shoutIDs or
+// in their echoes, so a page can link those @mentions without looking each one up.
+func MentionedUsernames(db *gorm.DB, shoutIDs []uint) (map[string]bool, error) {
+	mentioned := make(map[string]bool)
+	if len(shoutIDs) == 0 {
+		return mentioned, nil
+	}
+	var usernames []string
+	if err := db.Model(&Mention{}).
+		Joins("JOIN users ON users.id = mentions.user_id AND users.deleted_at IS NULL").
+		Where("mentions.shout_id IN ?", shoutIDs).
+		Distinct().Pluck("users.username", &usernames).Error; err != nil {
+		return nil, err
+	}
+	for _, username := range usernames {
+		mentioned[username] = true
+	}
+	return mentioned, nil
+}
+
+// MentionedUserIDs returns a subquery selecting the IDs of users mentioned directly in a shout
+// (echoID zero) or in a specific echo.
+func MentionedUserIDs(db *gorm.DB, shoutID, echoID uint) *gorm.DB {
+	return db.Model(&Mention{}).Select("user_id").Where("shout_id = ? AND echo_id = ?", shoutID, echoID)
+}
@@ -0,0 +1,41 @@
+package models_test
+
+import (
+	"testing"
+
+	"Void/internal/events"
+	"Void/internal/models"
+)
+
+func TestMentionedUsernames(t *testing.T) {
+	events.Transport = func([]byte) error { return nil }
+	conn := newTestDB(t)
+	alice := createUser(t, conn, "alice", "alice@example.com")
+	createUser(t, conn, "bob", "bob@example.com")
+	createUser(t, conn, "carol", "carol@example.com")
+	createUser(t, conn, "dave", "dave@example.com")
+
+	first := models.Shout{UserID: alice.ID, Content: "hi @bob and @nobody"}
+	second := models.Shout{UserID: alice.ID, Content: "hi @dave"}
+	for _, s := range []*models.Shout{&first, &second} {
+		if err := s.Create(conn); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	echo := models.Echo{ShoutID: first.ID, UserID: alice.ID, Content: "and @carol"}
+	if err := echo.Create(conn); err != nil {
+		t.Fatalf("Create echo: %v", err)
+	}
+
+	got, err := models.MentionedUsernames(conn, []uint{first.ID})
+	if err != nil {
+		t.Fatalf("MentionedUsernames: %v", err)
+	}
+	if len(got) != 2 || !got["bob"] || !got["carol"] {
+		t.Errorf("MentionedUsernames = %v, want bob and carol", got)
+	}
+
+	if got, err := models.MentionedUsernames(conn, nil); err != nil || len(got) != 0 {
+		t.Errorf("MentionedUsernames with no shouts = %v, %v; want none", got, err)
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MaxSuspension is the longest a single suspension may last.
+const MaxSuspension = 365 * 24 * time.Hour
+
+// SetUserStatus changes the user's account status. Suspensions last until the given time, which
+// is ignored for other statuses. Suspending or banning a user also signs them out of every session.
+func SetUserStatus(db *gorm.DB, userID uint, status string, until time.Time) error {
+	updates := map[string]interface{}{"status": status, "suspended_until": nil}
+	switch status {
+	case StatusSuspended:
+		if !until.After(time.Now()) || until.After(time.Now().Add(MaxSuspension)) {
+			return &ValidationError{Message: "a suspension must end in the future and last at most a year"}
+		}
+		updates["suspended_until"] = until
+		return signOutEverywhere(db, userID, updates)
+	case StatusBanned:
+		return signOutEverywhere(db, userID, updates)
+	case StatusActive, StatusShadowbanned:
+		result := db.Model(&User{}).Where("id = ?", userID).Updates(updates)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	}
+	return &ValidationError{Message: "unknown account status"}
+}
+
+// SuspendUser suspends the user until the given time; see SetUserStatus.
+func SuspendUser(db *gorm.DB, userID uint, until time.Time) error {
+	return SetUserStatus(db, userID, StatusSuspended, until)
+}
+
+// LockPassword replaces the user's password with a random one nobody knows and signs them out of
+// every session, so the account can only be recovered through a password reset.
+func LockPassword(db *gorm.DB, userID uint) error {
+	random, err := generateToken("")
+	if err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return signOutEverywhere(db, userID, map[string]interface{}{"password": string(hashed)})
+}
+
+// signOutEverywhere applies updates to the user, bumps their session version and drops their
+// session records in one transaction.
+func signOutEverywhere(db *gorm.DB, userID uint, updates map[string]interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		updates["session_version"] = gorm.Expr("session_version + 1")
+		result := tx.Model(&User{}).Where("id = ?", userID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("user_id = ?", userID).Delete(&UserSession{}).Error
+	})
+}
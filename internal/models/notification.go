@@ -1,14 +1,34 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"Void/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+// Notification types used as the Notification.Type discriminator.
+const (
+	NotificationNewShout = "new_shout"
+	NotificationEcho     = "echo"
+	NotificationMention  = "mention"
+	NotificationFollow   = "follow"
+	NotificationReport   = "report" // A moderator resolved the recipient's report
+)
 
 // Notification represents a notification for a user.
 type Notification struct {
 	gorm.Model
-	UserID         uint   `gorm:"not null"` // The recipient's ID
-	Message        string `gorm:"not null"` // The plain text message
+	UserID         uint   `gorm:"not null"`                   // The recipient's ID
+	Type           string `gorm:"not null;default:new_shout"` // One of the Notification* type constants
+	Message        string `gorm:"not null"`                   // The plain text message
 	AuthorUsername string // The username of the shout's author
 	AuthorAvatar   string // New field for the avatar URL
 	ShoutID        uint   // Optional: the ID of the related shout
+	EchoID         uint   // Optional: the ID of the related echo
 	Read           bool   `gorm:"default:false"`
 }
+
+// Cursor returns the notification's position for keyset pagination.
+func (n Notification) Cursor() pagination.Cursor {
+	return pagination.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
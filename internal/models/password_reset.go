@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long a password reset link stays valid.
+const PasswordResetTTL = time.Hour
+
+// ErrInvalidResetToken is returned for reset tokens that do not exist, have expired or were already used.
+var ErrInvalidResetToken = errors.New("this password reset link is invalid or has expired")
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password.
+// Only a SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+// NewPasswordResetToken stores a reset token for userID and returns its plaintext, which
+// belongs in the emailed link and cannot be recovered later.
+func NewPasswordResetToken(db *gorm.DB, userID uint) (string, error) {
+	plaintext, err := generateToken("")
+	if err != nil {
+		return "", err
+	}
+	token := PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(plaintext),
+		ExpiresAt: time.Now().Add(PasswordResetTTL),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// FindPasswordResetToken looks up the unused, unexpired reset token matching plaintext.
+// It returns ErrInvalidResetToken if there is none.
+func FindPasswordResetToken(db *gorm.DB, plaintext string) (*PasswordResetToken, error) {
+	var token PasswordResetToken
+	err := db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(plaintext), time.Now()).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ResetPassword sets a new password for the owner of the reset token. The token and any other
+// outstanding reset tokens for the user are used up, and the user's session version is bumped
+// so every existing session is signed out.
+func ResetPassword(db *gorm.DB, plaintext, password string) error {
+	if password == "" {
+		return &ValidationError{Message: "password cannot be empty"}
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		token, err := FindPasswordResetToken(tx, plaintext)
+		if err != nil {
+			return err
+		}
+
+		// Claim the token conditionally so two concurrent resets cannot both use it.
+		result := tx.Model(&PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		// Bumping the session version invalidates every existing session; drop their records too.
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&UserSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password":        string(hashed),
+			"session_version": gorm.Expr("session_version + 1"),
+		}).Error
+	})
+}
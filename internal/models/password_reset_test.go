@@ -0,0 +1,117 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"Void/internal/models"
+)
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+
+	token, err := models.NewPasswordResetToken(conn, user.ID)
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	other, err := models.NewPasswordResetToken(conn, user.ID)
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+
+	if err := models.ResetPassword(conn, token, "new password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := models.ResetPassword(conn, token, "another password"); !errors.Is(err, models.ErrInvalidResetToken) {
+		t.Errorf("reusing the token: err = %v, want ErrInvalidResetToken", err)
+	}
+	// Resetting uses up every outstanding link, not just the one that was followed.
+	if _, err := models.FindPasswordResetToken(conn, other); !errors.Is(err, models.ErrInvalidResetToken) {
+		t.Errorf("other outstanding token: err = %v, want ErrInvalidResetToken", err)
+	}
+
+	var updated models.User
+	conn.First(&updated, user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new password")) != nil {
+		t.Error("password was not changed to the first reset's password")
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+
+	token, err := models.NewPasswordResetToken(conn, user.ID)
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	if _, err := models.FindPasswordResetToken(conn, token); err != nil {
+		t.Fatalf("fresh token: %v", err)
+	}
+
+	conn.Model(&models.PasswordResetToken{}).Where("user_id = ?", user.ID).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := models.FindPasswordResetToken(conn, token); !errors.Is(err, models.ErrInvalidResetToken) {
+		t.Errorf("expired token: FindPasswordResetToken err = %v, want ErrInvalidResetToken", err)
+	}
+	if err := models.ResetPassword(conn, token, "new password"); !errors.Is(err, models.ErrInvalidResetToken) {
+		t.Errorf("expired token: ResetPassword err = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestResetPasswordSignsOutSessions(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+	bob := createUser(t, conn, "bob", "bob@example.com")
+
+	now := time.Now()
+	for _, s := range []models.UserSession{
+		{SessionID: "alice-laptop", UserID: user.ID, IP: "192.0.2.1", UserAgent: "laptop", CreatedAt: now, LastSeenAt: now},
+		{SessionID: "alice-phone", UserID: user.ID, IP: "192.0.2.2", UserAgent: "phone", CreatedAt: now, LastSeenAt: now},
+		{SessionID: "bob-laptop", UserID: bob.ID, IP: "192.0.2.3", UserAgent: "laptop", CreatedAt: now, LastSeenAt: now},
+	} {
+		if err := conn.Create(&s).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token, err := models.NewPasswordResetToken(conn, user.ID)
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	if err := models.ResetPassword(conn, token, "new password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	var updated models.User
+	conn.First(&updated, user.ID)
+	if updated.SessionVersion != user.SessionVersion+1 {
+		t.Errorf("session version = %d, want %d", updated.SessionVersion, user.SessionVersion+1)
+	}
+	var remaining []models.UserSession
+	conn.Order("session_id").Find(&remaining)
+	if len(remaining) != 1 || remaining[0].SessionID != "bob-laptop" {
+		t.Errorf("remaining sessions = %+v, want only bob's", remaining)
+	}
+}
+
+func TestResetPasswordRejectsEmptyPassword(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+
+	token, err := models.NewPasswordResetToken(conn, user.ID)
+	if err != nil {
+		t.Fatalf("NewPasswordResetToken: %v", err)
+	}
+	if err := models.ResetPassword(conn, token, ""); !models.IsValidationError(err) {
+		t.Fatalf("ResetPassword with an empty password: err = %v, want a validation error", err)
+	}
+	// A rejected attempt leaves the link usable.
+	if _, err := models.FindPasswordResetToken(conn, token); err != nil {
+		t.Errorf("token after a rejected reset: %v", err)
+	}
+}
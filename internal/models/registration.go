@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Registration modes control who may create an account.
+const (
+	RegistrationOpen     = "open"     // Anyone can register
+	RegistrationApproval = "approval" // New accounts wait for an admin's approval unless they have an invite
+	RegistrationInvite   = "invite"   // Registering requires an invite code
+	RegistrationClosed   = "closed"   // Nobody can register
+)
+
+// RegistrationModes lists every registration mode, in display order.
+var RegistrationModes = []string{RegistrationOpen, RegistrationApproval, RegistrationInvite, RegistrationClosed}
+
+// registrationModeSetting is the Setting key holding the registration mode.
+const registrationModeSetting = "registration_mode"
+
+// GetRegistrationMode returns the instance's registration mode, which is open until an admin changes it.
+func GetRegistrationMode(db *gorm.DB) (string, error) {
+	return GetSetting(db, registrationModeSetting, RegistrationOpen)
+}
+
+// SetRegistrationMode changes the instance's registration mode.
+func SetRegistrationMode(db *gorm.DB, mode string) error {
+	for _, m := range RegistrationModes {
+		if m == mode {
+			return SetSetting(db, registrationModeSetting, mode)
+		}
+	}
+	return &ValidationError{Message: "unknown registration mode"}
+}
+
+// RegisterUser creates u if the registration mode allows it. An invite code, when given, is
+// redeemed and lets the user skip the approval queue; in invite-only mode it is required. In
+// approval mode users without an invite are created with ApprovalPending set.
+func RegisterUser(db *gorm.DB, u *User, inviteCode string) error {
+	mode, err := GetRegistrationMode(db)
+	if err != nil {
+		return err
+	}
+	inviteCode = strings.TrimSpace(inviteCode)
+
+	switch {
+	case mode == RegistrationClosed:
+		return &ValidationError{Message: "registration is closed"}
+	case mode == RegistrationInvite && inviteCode == "":
+		return &ValidationError{Message: "registration is invite-only; enter your invite code"}
+	case mode == RegistrationApproval && inviteCode == "":
+		u.ApprovalPending = true
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if inviteCode != "" {
+			invite, err := redeemInvite(tx, inviteCode)
+			if err != nil {
+				return err
+			}
+			u.InviteID = &invite.ID
+		}
+		return u.Create(tx)
+	})
+}
+
+// PendingApprovals lists the users waiting for approval, oldest first.
+func PendingApprovals(db *gorm.DB) ([]User, error) {
+	var users []User
+	err := db.Where("approval_pending = ?", true).Order("created_at ASC").Find(&users).Error
+	return users, err
+}
+
+// ApproveUser lets a user waiting for approval log in and returns them. Users who are not waiting
+// are reported as gorm.ErrRecordNotFound.
+func ApproveUser(db *gorm.DB, userID uint) (*User, error) {
+	var user User
+	if err := db.Where("approval_pending = ?", true).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	user.ApprovalPending = false
+	if err := db.Model(&user).Update("approval_pending", false).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RejectUser deletes a user waiting for approval and returns them, freeing their username and email.
+// Users who are not waiting are reported as gorm.ErrRecordNotFound.
+func RejectUser(db *gorm.DB, userID uint) (*User, error) {
+	var user User
+	if err := db.Where("approval_pending = ?", true).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Delete(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
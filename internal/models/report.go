@@ -0,0 +1,303 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of things that can be reported, used as Report.TargetType.
+const (
+	ReportTargetShout = "shout"
+	ReportTargetEcho  = "echo"
+	ReportTargetUser  = "user"
+)
+
+// Report triage states, used as Report.Status.
+const (
+	ReportOpen      = "open"      // Waiting for a moderator
+	ReportActioned  = "actioned"  // A moderator hid the content or suspended its author
+	ReportDismissed = "dismissed" // A moderator found nothing to act on
+)
+
+// ReportStatuses lists every triage state, in the order the moderation queue shows them.
+var ReportStatuses = []string{ReportOpen, ReportActioned, ReportDismissed}
+
+// Moderator responses to a report, recorded as Report.Resolution.
+const (
+	ReportActionDismiss = "dismiss" // Close the report without acting
+	ReportActionHide    = "hide"    // Remove the reported shout or echo from every page
+	ReportActionSuspend = "suspend" // Suspend the reported user or the content's author
+)
+
+// MaxReportDetails is the longest explanation a reporter may add, in characters.
+const MaxReportDetails = 1000
+
+// ReportReason is a category a reporter picks from.
+type ReportReason struct {
+	Value string
+	Label string
+}
+
+// ReportReasons lists the reasons a report may give.
+var ReportReasons = []ReportReason{
+	{"spam", "Spam or scams"},
+	{"harassment", "Harassment or bullying"},
+	{"hate", "Hateful conduct"},
+	{"violence", "Threats or violence"},
+	{"sexual", "Sexual content"},
+	{"impersonation", "Impersonation"},
+	{"other", "Something else"},
+}
+
+// ReportReasonLabel returns the human-readable label for a reason value, or the value itself if it is unknown.
+func ReportReasonLabel(value string) string {
+	for _, reason := range ReportReasons {
+		if reason.Value == value {
+			return reason.Label
+		}
+	}
+	return value
+}
+
+// validReportReason reports whether value is one of ReportReasons.
+func validReportReason(value string) bool {
+	for _, reason := range ReportReasons {
+		if reason.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Report is a user's complaint about a shout, echo or user, waiting for or triaged by a moderator.
+type Report struct {
+	gorm.Model
+	ReporterID   uint   `gorm:"not null;index"`
+	Reporter     User   `gorm:"foreignKey:ReporterID"`
+	TargetType   string `gorm:"not null;index:idx_report_target"` // One of the ReportTarget* constants
+	TargetID     uint   `gorm:"not null;index:idx_report_target"`
+	TargetUserID uint   `gorm:"not null;index"` // The reported user, or the author of the reported content
+	TargetUser   User   `gorm:"foreignKey:TargetUserID"`
+	Reason       string `gorm:"not null"` // One of the ReportReasons values
+	Details      string // Optional explanation from the reporter
+	Status       string `gorm:"not null;default:open;index"` // One of the Report* triage states
+	Resolution   string // The ReportAction* a moderator took, once triaged
+	ResolvedByID *uint
+	ResolvedBy   *User `gorm:"foreignKey:ResolvedByID"`
+	ResolvedAt   *time.Time
+
+	Shout *Shout `gorm:"-"` // The reported shout, hidden or not, filled in by LoadReportTargets
+	Echo  *Echo  `gorm:"-"` // The reported echo, hidden or not, filled in by LoadReportTargets
+}
+
+// ReasonLabel returns the human-readable label of the report's reason.
+func (r Report) ReasonLabel() string {
+	return ReportReasonLabel(r.Reason)
+}
+
+// FileReport records reporterID's report about the shout, echo or user identified by targetType
+// and targetID. Reporting yourself, reporting something twice while the first report is open and
+// reasons outside ReportReasons are validation errors.
+func FileReport(db *gorm.DB, reporterID uint, targetType string, targetID uint, reason, details string) (*Report, error) {
+	if !validReportReason(reason) {
+		return nil, &ValidationError{Message: "choose a reason for the report"}
+	}
+	details = strings.TrimSpace(details)
+	if len([]rune(details)) > MaxReportDetails {
+		return nil, &ValidationError{Message: "please keep the details under 1000 characters"}
+	}
+
+	targetUserID, err := reportTargetUserID(db, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if targetUserID == reporterID {
+		return nil, &ValidationError{Message: "you can't report yourself"}
+	}
+
+	var open int64
+	if err := db.Model(&Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?", reporterID, targetType, targetID, ReportOpen).
+		Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, &ValidationError{Message: "you've already reported this, and a moderator will look at it soon"}
+	}
+
+	report := &Report{
+		ReporterID:   reporterID,
+		TargetType:   targetType,
+		TargetID:     targetID,
+		TargetUserID: targetUserID,
+		Reason:       reason,
+		Details:      details,
+		Status:       ReportOpen,
+	}
+	if err := db.Create(report).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// reportTargetUserID returns the user a report is about: the author of a shout or echo, or the user themselves.
+func reportTargetUserID(db *gorm.DB, targetType string, targetID uint) (uint, error) {
+	var err error
+	var userID uint
+	switch targetType {
+	case ReportTargetShout:
+		var shout Shout
+		err = db.Select("user_id").First(&shout, targetID).Error
+		userID = shout.UserID
+	case ReportTargetEcho:
+		var echo Echo
+		err = db.Select("user_id").First(&echo, targetID).Error
+		userID = echo.UserID
+	case ReportTargetUser:
+		var user User
+		err = db.Select("id").First(&user, targetID).Error
+		userID = user.ID
+	default:
+		return 0, &ValidationError{Message: "that can't be reported"}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && userID == 0 {
+		return 0, &ValidationError{Message: "that can't be reported"}
+	}
+	return userID, err
+}
+
+// ReportsWithStatus returns up to limit reports in the given triage state with their reporters and
+// targets. Open reports come oldest first, so the longest-waiting are handled first; triaged
+// reports come most recently resolved first.
+func ReportsWithStatus(db *gorm.DB, status string, limit int) ([]Report, error) {
+	order := "created_at ASC"
+	if status != ReportOpen {
+		order = "resolved_at DESC"
+	}
+	var reports []Report
+	err := db.Preload("Reporter").Preload("TargetUser").Preload("ResolvedBy").
+		Where("status = ?", status).Order(order).Limit(limit).Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	return reports, LoadReportTargets(db, reports)
+}
+
+// LoadReportTargets fills in the Shout or Echo of each content report, including content that has
+// since been hidden or deleted, so moderators can see what was reported.
+func LoadReportTargets(db *gorm.DB, reports []Report) error {
+	var shoutIDs, echoIDs []uint
+	for _, report := range reports {
+		switch report.TargetType {
+		case ReportTargetShout:
+			shoutIDs = append(shoutIDs, report.TargetID)
+		case ReportTargetEcho:
+			echoIDs = append(echoIDs, report.TargetID)
+		}
+	}
+
+	shouts := map[uint]*Shout{}
+	if len(shoutIDs) > 0 {
+		var found []Shout
+		if err := db.Unscoped().Where("id IN ?", shoutIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for i := range found {
+			shouts[found[i].ID] = &found[i]
+		}
+	}
+	echoes := map[uint]*Echo{}
+	if len(echoIDs) > 0 {
+		var found []Echo
+		if err := db.Unscoped().Where("id IN ?", echoIDs).Find(&found).Error; err != nil {
+			return err
+		}
+		for i := range found {
+			echoes[found[i].ID] = &found[i]
+		}
+	}
+
+	for i := range reports {
+		switch reports[i].TargetType {
+		case ReportTargetShout:
+			reports[i].Shout = shouts[reports[i].TargetID]
+		case ReportTargetEcho:
+			reports[i].Echo = echoes[reports[i].TargetID]
+		}
+	}
+	return nil
+}
+
+// CountOpenReports returns the number of reports waiting for a moderator.
+func CountOpenReports(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&Report{}).Where("status = ?", ReportOpen).Count(&count).Error
+	return count, err
+}
+
+// ResolveReport applies a moderator's action to an open report: it hides the reported content,
+// suspends the reported user until suspendUntil, or dismisses the report. Every other open report
+// about the same target is resolved the same way. It returns the resolved reports, so their
+// reporters can be told the outcome.
+func ResolveReport(db *gorm.DB, report *Report, moderatorID uint, action string, suspendUntil time.Time) ([]Report, error) {
+	if report.Status != ReportOpen {
+		return nil, &ValidationError{Message: "this report has already been resolved"}
+	}
+
+	status := ReportActioned
+	var resolved []Report
+	err := db.Transaction(func(tx *gorm.DB) error {
+		switch action {
+		case ReportActionDismiss:
+			status = ReportDismissed
+		case ReportActionHide:
+			if err := hideReportTarget(tx, report); err != nil {
+				return err
+			}
+		case ReportActionSuspend:
+			if err := SuspendUser(tx, report.TargetUserID, suspendUntil); err != nil {
+				return err
+			}
+		default:
+			return &ValidationError{Message: "unknown moderation action"}
+		}
+
+		if err := tx.Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, ReportOpen).
+			Find(&resolved).Error; err != nil {
+			return err
+		}
+		ids := make([]uint, len(resolved))
+		for i, r := range resolved {
+			ids[i] = r.ID
+		}
+		return tx.Model(&Report{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":         status,
+			"resolution":     action,
+			"resolved_by_id": moderatorID,
+			"resolved_at":    time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resolved {
+		resolved[i].Status = status
+		resolved[i].Resolution = action
+	}
+	return resolved, nil
+}
+
+// hideReportTarget removes a reported shout or echo from every page. It is soft-deleted, so it
+// stays in the database for the moderation queue.
+func hideReportTarget(db *gorm.DB, report *Report) error {
+	switch report.TargetType {
+	case ReportTargetShout:
+		return db.Delete(&Shout{}, report.TargetID).Error
+	case ReportTargetEcho:
+		return db.Delete(&Echo{}, report.TargetID).Error
+	}
+	return &ValidationError{Message: "only shouts and echoes can be hidden; suspend the user instead"}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "gorm.io/gorm"
+
+// Roles, from least to most privileged. Each role can do everything the roles below it can.
+const (
+	RoleUser      = "user"      // A regular member
+	RoleModerator = "moderator" // Can also remove other people's content
+	RoleAdmin     = "admin"     // Can also administer the instance
+)
+
+// Roles lists every role, from least to most privileged.
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// roleRank orders roles by privilege, returning zero for unknown roles.
+func roleRank(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleModerator:
+		return 2
+	case RoleUser, "": // Empty when the role column was not loaded
+		return 1
+	}
+	return 0
+}
+
+// HasRole reports whether the user has role or a more privileged one.
+func (u User) HasRole(role string) bool {
+	return ValidRole(role) && roleRank(u.Role) >= roleRank(role)
+}
+
+// Outranks reports whether the user's role is more privileged than other's.
+func (u User) Outranks(other User) bool {
+	return roleRank(u.Role) > roleRank(other.Role)
+}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	return role != "" && roleRank(role) > 0
+}
+
+// SetRole changes the user's role.
+func (u *User) SetRole(db *gorm.DB, role string) error {
+	if !ValidRole(role) {
+		return &ValidationError{Message: "unknown role"}
+	}
+	u.Role = role
+	return db.Model(u).Update("role", role).Error
+}
@@ -1,11 +1,10 @@
 package models
 
 import (
-	"encoding/json"
-	"errors"
 	"log"
 
-	"Void/pkg/rabbitmq"
+	"Void/internal/events"
+	"Void/internal/pagination"
 
 	"gorm.io/gorm"
 )
@@ -17,6 +16,8 @@ type Shout struct {
 	UserID  uint   `gorm:"not null"`
 	User    User   // Association to the user.
 	Echoes  []Echo `gorm:"foreignKey:ShoutID"`
+
+	EchoCount int64 `gorm:"-"` // Number of echoes, filled in by LoadEchoCounts instead of preloading Echoes.
 }
 
 // ShoutCreatedEvent is the event payload for when a shout is created.
@@ -68,10 +69,23 @@ func (e ShoutCreatedEvent) GetAvatar() string {
 	return e.Avatar
 }
 
-// Create persists the shout using the provided DB instance and publishes a notification event.
+// Create persists the shout and any @mentions and #hashtags it contains using the provided DB instance,
+// then publishes a ShoutCreatedEvent for it. It also performs a simple validation to ensure content is not empty.
 func (s *Shout) Create(db *gorm.DB) error {
-	// Save the shout to the database using the injected DB.
-	if err := db.Create(s).Error; err != nil {
+	if s.Content == "" {
+		return &ValidationError{Message: "shout content cannot be empty"}
+	}
+
+	// Save the shout, its mentions and its tags to the database using the injected DB.
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		if err := createMentions(tx, s.Content, s.UserID, s.ID, 0); err != nil {
+			return err
+		}
+		return syncTags(tx, s)
+	}); err != nil {
 		return err
 	}
 
@@ -81,38 +95,60 @@ func (s *Shout) Create(db *gorm.DB) error {
 		// Continue even if loading the user fails.
 	}
 
-	// Create an event payload using the shared type.
-	eventPayload := ShoutCreatedEvent{
-		ShoutID:  s.ID,
-		Content:  s.Content,
-		UserID:   s.UserID,
-		Username: s.User.Username,
-	}
+	// Publish the event using the interface-based function.
+	return events.PublishShoutEvent(s.ToEvent())
+}
 
-	// Marshal the event payload to JSON.
-	msg, err := json.Marshal(eventPayload)
-	if err != nil {
-		log.Printf("Failed to marshal event payload: %v", err)
-		return err
+// Cursor returns the shout's position for keyset pagination.
+func (s Shout) Cursor() pagination.Cursor {
+	return pagination.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+// LoadEchoCounts fills in EchoCount for each shout with a single aggregate query.
+func LoadEchoCounts(db *gorm.DB, shouts []Shout) error {
+	if len(shouts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(shouts))
+	for i, shout := range shouts {
+		ids[i] = shout.ID
 	}
 
-	// Publish the notification event.
-	if err := rabbitmq.PublishNotification(msg); err != nil {
-		log.Printf("Failed to publish notification: %v", err)
+	var rows []struct {
+		ShoutID uint
+		Count   int64
+	}
+	if err := db.Model(&Echo{}).
+		Select("shout_id, COUNT(*) AS count").
+		Where("shout_id IN ?", ids).
+		Group("shout_id").
+		Scan(&rows).Error; err != nil {
 		return err
 	}
 
-	log.Printf("Published notification for shout ID: %d", s.ID)
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ShoutID] = row.Count
+	}
+	for i := range shouts {
+		shouts[i].EchoCount = counts[shouts[i].ID]
+	}
 	return nil
 }
 
-// UpdateContent updates the shout's content with some basic validation.
+// UpdateContent updates the shout's content with some basic validation
+// and re-syncs its #hashtags with the new content.
 func (s *Shout) UpdateContent(db *gorm.DB, newContent string) error {
 	if newContent == "" {
-		return errors.New("new content cannot be empty")
+		return &ValidationError{Message: "new content cannot be empty"}
 	}
 	s.Content = newContent
-	return db.Save(s).Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(s).Error; err != nil {
+			return err
+		}
+		return syncTags(tx, s)
+	})
 }
 
 // Delete removes the shout from the database.
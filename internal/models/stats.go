@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InstanceTotals counts the instance's users and content.
+type InstanceTotals struct {
+	Users            int64
+	Shouts           int64
+	Echoes           int64
+	PendingApprovals int64
+	Suspended        int64
+	Banned           int64
+	Shadowbanned     int64
+	OpenReports      int64
+}
+
+// DailyActivity counts the users, shouts and echoes created on one day.
+type DailyActivity struct {
+	Day    string // YYYY-MM-DD, in UTC
+	Users  int64
+	Shouts int64
+	Echoes int64
+}
+
+// GetInstanceTotals counts every user, shout and echo, plus the accounts waiting for approval or
+// restricted and the reports waiting for a moderator.
+func GetInstanceTotals(db *gorm.DB) (InstanceTotals, error) {
+	var totals InstanceTotals
+	counts := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{db.Model(&User{}), &totals.Users},
+		{db.Model(&Shout{}), &totals.Shouts},
+		{db.Model(&Echo{}), &totals.Echoes},
+		{db.Model(&User{}).Where("approval_pending = ?", true), &totals.PendingApprovals},
+		{db.Model(&User{}).Where("status = ? AND suspended_until > ?", StatusSuspended, time.Now()), &totals.Suspended},
+		{db.Model(&User{}).Where("status = ?", StatusBanned), &totals.Banned},
+		{db.Model(&User{}).Where("status = ?", StatusShadowbanned), &totals.Shadowbanned},
+		{db.Model(&Report{}).Where("status = ?", ReportOpen), &totals.OpenReports},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return totals, err
+		}
+	}
+	return totals, nil
+}
+
+// RecentActivity returns the number of users, shouts and echoes created on each of the last days
+// days, oldest first, including days with no activity.
+func RecentActivity(db *gorm.DB, days int) ([]DailyActivity, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-days)
+
+	activity := make([]DailyActivity, days)
+	index := make(map[string]*DailyActivity, days)
+	for i := range activity {
+		activity[i].Day = since.AddDate(0, 0, i).Format("2006-01-02")
+		index[activity[i].Day] = &activity[i]
+	}
+
+	tables := []struct {
+		model interface{}
+		field func(*DailyActivity) *int64
+	}{
+		{&User{}, func(d *DailyActivity) *int64 { return &d.Users }},
+		{&Shout{}, func(d *DailyActivity) *int64 { return &d.Shouts }},
+		{&Echo{}, func(d *DailyActivity) *int64 { return &d.Echoes }},
+	}
+	for _, table := range tables {
+		var rows []struct {
+			Day   string
+			Count int64
+		}
+		if err := db.Model(table.model).
+			Select("date(created_at) AS day, COUNT(*) AS count").
+			Where("created_at >= ?", since).
+			Group("day").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if day, ok := index[row.Day]; ok {
+				*table.field(day) = row.Count
+			}
+		}
+	}
+	return activity, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag represents a #hashtag that shouts can be organized under.
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;not null"` // Lower-cased tag name without the leading '#'
+}
+
+// ShoutTag links a shout to a tag it contains.
+type ShoutTag struct {
+	ShoutID   uint      `gorm:"primaryKey"`
+	TagID     uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// TrendingTag is a tag together with the number of shouts using it within a time window.
+type TrendingTag struct {
+	Name  string
+	Count int64
+}
+
+// syncTags makes the shout's tags match the hashtags currently present in its content. Tags the
+// shout keeps are left alone, so editing a shout does not make its tags look newly used.
+func syncTags(db *gorm.DB, shout *Shout) error {
+	var current []ShoutTag
+	if err := db.Where("shout_id = ?", shout.ID).Find(&current).Error; err != nil {
+		return err
+	}
+	stale := make(map[uint]bool, len(current))
+	for _, st := range current {
+		stale[st.TagID] = true
+	}
+
+	var added []ShoutTag
+	for _, name := range ParseHashtags(shout.Content) {
+		tag := Tag{Name: name}
+		if err := db.Where(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		if _, ok := stale[tag.ID]; ok {
+			delete(stale, tag.ID)
+			continue
+		}
+		added = append(added, ShoutTag{ShoutID: shout.ID, TagID: tag.ID})
+	}
+
+	if len(stale) > 0 {
+		removed := make([]uint, 0, len(stale))
+		for id := range stale {
+			removed = append(removed, id)
+		}
+		if err := db.Where("shout_id = ? AND tag_id IN ?", shout.ID, removed).Delete(&ShoutTag{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return db.Create(&added).Error
+}
+
+// TaggedShoutIDs returns a subquery selecting the IDs of every shout tagged with name.
+func TaggedShoutIDs(db *gorm.DB, name string) *gorm.DB {
+	return db.Model(&ShoutTag{}).
+		Select("shout_tags.shout_id").
+		Joins("JOIN tags ON tags.id = shout_tags.tag_id").
+		Where("tags.name = ?", name)
+}
+
+// TrendingTags returns the most used tags on shouts posted since the given time, most used first.
+// Shouts by suspended, banned and shadowbanned users do not count.
+func TrendingTags(db *gorm.DB, since time.Time, limit int) ([]TrendingTag, error) {
+	var trending []TrendingTag
+	err := db.Model(&ShoutTag{}).
+		Select("tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = shout_tags.tag_id").
+		Joins("JOIN shouts ON shouts.id = shout_tags.shout_id AND shouts.deleted_at IS NULL").
+		Where("shouts.created_at >= ?", since).
+		Where("shouts.user_id NOT IN (?)", HiddenAuthorIDs(db, 0)).
+		Group("tags.name").
+		Order("count DESC, tags.name").
+		Limit(limit).
+		Scan(&trending).Error
+	return trending, err
+}
@@ -0,0 +1,56 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"Void/internal/events"
+	"Void/internal/models"
+)
+
+func TestEditingShoutDoesNotRetrendTags(t *testing.T) {
+	events.Transport = func([]byte) error { return nil }
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+
+	shout := models.Shout{UserID: user.ID, Content: "hello #old #kept"}
+	if err := shout.Create(conn); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// Age the shout and its tags past the trending window.
+	posted := time.Now().Add(-48 * time.Hour)
+	conn.Model(&models.Shout{}).Where("id = ?", shout.ID).UpdateColumn("created_at", posted)
+	conn.Model(&models.ShoutTag{}).Where("shout_id = ?", shout.ID).UpdateColumn("created_at", posted)
+
+	shout.CreatedAt = posted
+	if err := shout.UpdateContent(conn, "hello #kept #new"); err != nil {
+		t.Fatalf("UpdateContent: %v", err)
+	}
+
+	var names []string
+	conn.Model(&models.ShoutTag{}).Joins("JOIN tags ON tags.id = shout_tags.tag_id").
+		Where("shout_tags.shout_id = ?", shout.ID).Order("tags.name").Pluck("tags.name", &names)
+	if len(names) != 2 || names[0] != "kept" || names[1] != "new" {
+		t.Errorf("tags after edit = %v, want [kept new]", names)
+	}
+
+	trending, err := models.TrendingTags(conn, time.Now().Add(-24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("TrendingTags: %v", err)
+	}
+	if len(trending) != 0 {
+		t.Errorf("editing an old shout made %v trend, want nothing", trending)
+	}
+
+	fresh := models.Shout{UserID: user.ID, Content: "now #kept"}
+	if err := fresh.Create(conn); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	trending, err = models.TrendingTags(conn, time.Now().Add(-24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("TrendingTags: %v", err)
+	}
+	if len(trending) != 1 || trending[0].Name != "kept" || trending[0].Count != 1 {
+		t.Errorf("TrendingTags = %v, want [{kept 1}]", trending)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// generateToken returns a new random secret: prefix followed by 32 random bytes, base64url-encoded.
+func generateToken(prefix string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 of a plaintext token. Tokens are long random
+// strings, so a fast hash is sufficient; unlike passwords they cannot be guessed from a dictionary.
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Void/pkg/totp"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued when two-factor authentication is enabled.
+const RecoveryCodeCount = 10
+
+// errInvalidCode is returned when a two-factor code does not match.
+var errInvalidCode = &ValidationError{Message: "invalid authentication code"}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the user has lost their
+// authenticator. Only a SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"not null"`
+	UsedAt   *time.Time
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for u and stores it, still disabled, until
+// EnableTOTP confirms the user's authenticator produces matching codes.
+func (u *User) BeginTOTPEnrollment(db *gorm.DB) error {
+	if u.TOTPEnabled {
+		return &ValidationError{Message: "two-factor authentication is already enabled"}
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	u.TOTPSecret = secret
+	return db.Model(u).UpdateColumn("totp_secret", secret).Error
+}
+
+// EnableTOTP turns on two-factor authentication once code matches the secret from
+// BeginTOTPEnrollment. It returns freshly generated recovery codes, which cannot be recovered later.
+func (u *User) EnableTOTP(db *gorm.DB, code string) ([]string, error) {
+	if u.TOTPEnabled {
+		return nil, &ValidationError{Message: "two-factor authentication is already enabled"}
+	}
+	if u.TOTPSecret == "" {
+		return nil, &ValidationError{Message: "start two-factor setup first"}
+	}
+	step, ok := totp.Validate(u.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(u).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, u.ID)
+		return err
+	})
+	return codes, err
+}
+
+// DisableTOTP turns off two-factor authentication after checking a current TOTP or recovery code,
+// and deletes the user's secret and recovery codes.
+func (u *User) DisableTOTP(db *gorm.DB, code string) error {
+	if !u.TOTPEnabled {
+		return &ValidationError{Message: "two-factor authentication is not enabled"}
+	}
+	if err := u.VerifySecondFactor(db, code); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(u).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", u.ID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// VerifySecondFactor checks a login's second factor: either a current TOTP code or an unused
+// recovery code, which is then used up. A TOTP code is rejected if it, or a later one, was
+// already accepted, so an intercepted code cannot be replayed.
+func (u *User) VerifySecondFactor(db *gorm.DB, code string) error {
+	if step, ok := totp.Validate(u.TOTPSecret, code, time.Now()); ok {
+		result := db.Model(&User{}).
+			Where("id = ? AND totp_last_step < ?", u.ID, step).
+			UpdateColumn("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidCode
+		}
+		u.TOTPLastStep = step
+		return nil
+	}
+
+	result := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", u.ID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with a fresh set and returns them.
+func (u *User) RegenerateRecoveryCodes(db *gorm.DB) ([]string, error) {
+	if !u.TOTPEnabled {
+		return nil, &ValidationError{Message: "two-factor authentication is not enabled"}
+	}
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, u.ID)
+		return err
+	})
+	return codes, err
+}
+
+// RemainingRecoveryCodes counts the user's unused recovery codes.
+func (u User) RemainingRecoveryCodes(db *gorm.DB) int64 {
+	var count int64
+	db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", u.ID).Count(&count)
+	return count
+}
+
+// replaceRecoveryCodes deletes userID's recovery codes and stores RecoveryCodeCount new ones,
+// returning their plaintext formatted as "xxxxx-xxxxx".
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	rows := make([]RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		// 56 random bits is plenty for a single-use code, and short enough to type.
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		rows[i] = RecoveryCode{UserID: userID, CodeHash: hashToken(code)}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips the formatting users may type around a recovery code.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
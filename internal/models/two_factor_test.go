@@ -0,0 +1,112 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Void/internal/models"
+	"Void/pkg/totp"
+)
+
+// enableTOTP enrolls user in two-factor authentication and returns their recovery codes.
+func enableTOTP(t *testing.T, conn *gorm.DB, user *models.User) []string {
+	t.Helper()
+	if err := user.BeginTOTPEnrollment(conn); err != nil {
+		t.Fatalf("BeginTOTPEnrollment: %v", err)
+	}
+	code, err := totp.Code(user.TOTPSecret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := user.EnableTOTP(conn, code)
+	if err != nil {
+		t.Fatalf("EnableTOTP: %v", err)
+	}
+	conn.First(user, user.ID)
+	return codes
+}
+
+func TestEnableTOTPRejectsWrongCode(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+	if err := user.BeginTOTPEnrollment(conn); err != nil {
+		t.Fatalf("BeginTOTPEnrollment: %v", err)
+	}
+	if _, err := user.EnableTOTP(conn, "000000"); !models.IsValidationError(err) {
+		t.Errorf("EnableTOTP with a wrong code: err = %v, want a validation error", err)
+	}
+	conn.First(&user, user.ID)
+	if user.TOTPEnabled {
+		t.Error("two-factor authentication was enabled by a wrong code")
+	}
+}
+
+func TestVerifySecondFactorRejectsReplayedCodes(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+	enableTOTP(t, conn, &user)
+	step := user.TOTPLastStep
+
+	// The code that enabled two-factor authentication cannot be used to log in.
+	current, _ := totp.Code(user.TOTPSecret, step)
+	if err := user.VerifySecondFactor(conn, current); err == nil {
+		t.Error("the code used to enable two-factor authentication was accepted again")
+	}
+
+	next, _ := totp.Code(user.TOTPSecret, step+1)
+	if err := user.VerifySecondFactor(conn, next); err != nil {
+		t.Fatalf("code for the next step: %v", err)
+	}
+	if err := user.VerifySecondFactor(conn, next); err == nil {
+		t.Error("a replayed code was accepted")
+	}
+
+	// The previous step is still within the skew window, but older than the last accepted code.
+	var reloaded models.User
+	conn.First(&reloaded, user.ID)
+	if reloaded.TOTPLastStep != step+1 {
+		t.Errorf("totp_last_step = %d, want %d", reloaded.TOTPLastStep, step+1)
+	}
+	if err := reloaded.VerifySecondFactor(conn, current); err == nil {
+		t.Error("a code older than the last accepted one was accepted")
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	conn := newTestDB(t)
+	user := createUser(t, conn, "alice", "alice@example.com")
+	codes := enableTOTP(t, conn, &user)
+	if len(codes) != models.RecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), models.RecoveryCodeCount)
+	}
+
+	// Codes are accepted however the user formats them.
+	typed := " " + strings.ToUpper(strings.Replace(codes[0], "-", " ", 1)) + " "
+	if err := user.VerifySecondFactor(conn, typed); err != nil {
+		t.Fatalf("recovery code %q: %v", typed, err)
+	}
+	if err := user.VerifySecondFactor(conn, codes[0]); err == nil {
+		t.Error("a used recovery code was accepted again")
+	}
+	if got := user.RemainingRecoveryCodes(conn); got != models.RecoveryCodeCount-1 {
+		t.Errorf("remaining recovery codes = %d, want %d", got, models.RecoveryCodeCount-1)
+	}
+	if err := user.VerifySecondFactor(conn, strings.Replace(codes[1], "-", "", 1)); err != nil {
+		t.Errorf("recovery code without its dash: %v", err)
+	}
+
+	// Regenerating replaces every code, used or not.
+	fresh, err := user.RegenerateRecoveryCodes(conn)
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if err := user.VerifySecondFactor(conn, codes[2]); err == nil {
+		t.Error("a recovery code from before regenerating was accepted")
+	}
+	if err := user.VerifySecondFactor(conn, fresh[0]); err != nil {
+		t.Errorf("a regenerated recovery code: %v", err)
+	}
+}
@@ -1,13 +1,78 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // User represents a registered user.
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null"`
-	Email    string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
-	Avatar   string // Path to avatar file
-	Bio      string // Optional user bio
+	Username           string     `gorm:"unique;not null"`
+	Email              string     `gorm:"unique;not null"`
+	Password           string     `gorm:"not null"`
+	Avatar             string     // Path to avatar file
+	Bio                string     // Optional user bio
+	SessionVersion     uint       `gorm:"not null;default:0"`     // Bumped to sign the user out of every existing session
+	Verified           bool       `gorm:"not null;default:false"` // Whether the user has confirmed their email address
+	VerificationSentAt *time.Time // When the latest verification email was sent
+	TOTPSecret         string     `gorm:"column:totp_secret"`                         // Base32 TOTP secret; set during enrollment
+	TOTPEnabled        bool       `gorm:"column:totp_enabled;not null;default:false"` // Whether login requires a second factor
+	TOTPLastStep       int64      `gorm:"column:totp_last_step;not null;default:0"`   // Time step of the last accepted TOTP code
+	Role               string     `gorm:"not null;default:user"`                      // RoleUser, RoleModerator or RoleAdmin
+	ApprovalPending    bool       `gorm:"not null;default:false"`                     // Registered while approval was required and not yet approved
+	InviteID           *uint      // The invite the user registered with, if any
+	Status             string     `gorm:"not null;default:active"` // One of the Status* constants
+	SuspendedUntil     *time.Time // When a suspension ends
+}
+
+// Create validates and persists a new user. Taken usernames and emails are reported as
+// validation errors rather than database errors.
+func (u *User) Create(db *gorm.DB) error {
+	u.Username = strings.TrimSpace(u.Username)
+	u.Email = strings.TrimSpace(u.Email)
+	if u.Username == "" || u.Email == "" || u.Password == "" {
+		return &ValidationError{Message: "all fields are required"}
+	}
+
+	var taken User
+	err := db.Select("username", "email").
+		Where("username = ? OR email = ?", u.Username, u.Email).
+		First(&taken).Error
+	switch {
+	case err == nil && taken.Username == u.Username:
+		return &ValidationError{Message: "that username is already taken"}
+	case err == nil:
+		return &ValidationError{Message: "an account with that email already exists"}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	if err := db.Create(u).Error; err != nil {
+		// Lost a race with another registration for the same username or email.
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return &ValidationError{Message: "that username or email is already taken"}
+		}
+		return err
+	}
+	return nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, using a backslash as the escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchUsers returns up to limit users, newest first, whose username or email contains q.
+// An empty q matches everyone.
+func SearchUsers(db *gorm.DB, q string, limit int) ([]User, error) {
+	query := db.Order("created_at DESC").Limit(limit)
+	if q != "" {
+		pattern := "%" + likeEscaper.Replace(q) + "%"
+		query = query.Where(`username LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	var users []User
+	err := query.Find(&users).Error
+	return users, err
 }
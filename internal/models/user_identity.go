@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// maxUsernameLength caps usernames generated for accounts created through an identity provider.
+const maxUsernameLength = 30
+
+// UserIdentity links a user to an account at an external OpenID Connect provider, so signing in
+// there signs them in to Void.
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_user_identities_subject"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_user_identities_subject"` // The provider's stable user ID ("sub" claim)
+	Email     string    // The email the provider reported when the identity was linked
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// ExternalIdentity is who an identity provider says is signing in.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string // Preferred username, used when a new account is created
+}
+
+// SignInWithIdentity returns the user that id signs in as. An identity seen before signs in as the
+// user it is linked to. Otherwise it is linked to the user with the same email address, provided
+// the provider has verified that address, or else a new user is created if the registration mode
+// allows it without an invite. created reports whether a new user was created. Identities that
+// cannot be linked safely are reported as validation errors.
+func SignInWithIdentity(db *gorm.DB, id ExternalIdentity) (user User, created bool, err error) {
+	if id.Provider == "" || id.Subject == "" {
+		return user, false, errors.New("identity has no provider or subject")
+	}
+
+	var linked UserIdentity
+	err = db.Where("provider = ? AND subject = ?", id.Provider, id.Subject).First(&linked).Error
+	if err == nil {
+		err = db.First(&user, linked.UserID).Error
+		return user, false, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, false, err
+	}
+
+	id.Email = strings.TrimSpace(id.Email)
+	if id.Email == "" {
+		return user, false, &ValidationError{Message: "your account with that provider did not share an email address"}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = ?", strings.ToLower(id.Email)).First(&user).Error
+		switch {
+		case err == nil:
+			// Only an address the provider has verified proves the user owns the existing account.
+			if !id.EmailVerified {
+				return &ValidationError{Message: "an account with that email already exists; log in with your password instead"}
+			}
+			if !user.Verified {
+				user.Verified = true
+				if err := tx.Model(&user).Update("verified", true).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			mode, err := GetRegistrationMode(tx)
+			if err != nil {
+				return err
+			}
+			switch mode {
+			case RegistrationClosed:
+				return &ValidationError{Message: "registration is closed, so no new account can be created"}
+			case RegistrationInvite:
+				return &ValidationError{Message: "registration is invite-only; register with your invite code first"}
+			}
+			if user, err = newIdentityUser(tx, id, mode == RegistrationApproval); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&UserIdentity{
+			UserID:   user.ID,
+			Provider: id.Provider,
+			Subject:  id.Subject,
+			Email:    id.Email,
+		}).Error
+	})
+	return user, created, err
+}
+
+// newIdentityUser creates a user for a first sign-in through an identity provider. The username is
+// derived from the preferred username or email, numbered if taken. The account gets a random
+// password nobody knows; the user can set one with a password reset. pending marks the user as
+// waiting for approval.
+func newIdentityUser(db *gorm.DB, id ExternalIdentity, pending bool) (User, error) {
+	secret, err := generateToken("")
+	if err != nil {
+		return User{}, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	base := usernameFrom(id.Username)
+	if base == "" {
+		local, _, _ := strings.Cut(id.Email, "@")
+		base = usernameFrom(local)
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for n := 2; ; n++ {
+		var taken int64
+		if err := db.Model(&User{}).Where("username = ?", username).Count(&taken).Error; err != nil {
+			return User{}, err
+		}
+		if taken == 0 {
+			break
+		}
+		suffix := strconv.Itoa(n)
+		username = base[:min(len(base), maxUsernameLength-len(suffix))] + suffix
+	}
+
+	user := User{Username: username, Email: id.Email, Password: string(hashed), Verified: id.EmailVerified, ApprovalPending: pending}
+	return user, user.Create(db)
+}
+
+// usernameFrom keeps the letters, digits and underscores of s, which are the characters an
+// @mention can contain, truncated to maxUsernameLength.
+func usernameFrom(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	if len(username) > maxUsernameLength {
+		username = username[:maxUsernameLength]
+	}
+	return username
+}
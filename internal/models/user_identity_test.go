@@ -0,0 +1,104 @@
+package models_test
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"Void/internal/models"
+)
+
+func identityCount(t *testing.T, conn *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := conn.Model(&models.UserIdentity{}).Count(&count).Error; err != nil {
+		t.Fatalf("counting identities: %v", err)
+	}
+	return count
+}
+
+func TestSignInWithIdentityLinksVerifiedEmail(t *testing.T) {
+	conn := newTestDB(t)
+	alice := createUser(t, conn, "alice", "Alice@Example.com")
+
+	id := models.ExternalIdentity{Provider: "mock", Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true}
+	user, created, err := models.SignInWithIdentity(conn, id)
+	if err != nil {
+		t.Fatalf("SignInWithIdentity: %v", err)
+	}
+	if created || user.ID != alice.ID {
+		t.Fatalf("signed in as user %d (created %v), want existing user %d", user.ID, created, alice.ID)
+	}
+
+	// The identity is now linked, so it signs in as alice even without an email.
+	user, created, err = models.SignInWithIdentity(conn, models.ExternalIdentity{Provider: "mock", Subject: "alice-sub"})
+	if err != nil || created || user.ID != alice.ID {
+		t.Fatalf("second sign-in = user %d, created %v, err %v; want user %d", user.ID, created, err, alice.ID)
+	}
+}
+
+func TestSignInWithIdentityRefusesUnverifiedEmail(t *testing.T) {
+	conn := newTestDB(t)
+	createUser(t, conn, "alice", "alice@example.com")
+
+	id := models.ExternalIdentity{Provider: "mock", Subject: "mallory-sub", Email: "alice@example.com", EmailVerified: false}
+	_, _, err := models.SignInWithIdentity(conn, id)
+	if !models.IsValidationError(err) {
+		t.Fatalf("SignInWithIdentity error = %v, want a validation error", err)
+	}
+	if n := identityCount(t, conn); n != 0 {
+		t.Errorf("%d identities linked, want 0", n)
+	}
+}
+
+func TestSignInWithIdentityCreatesUser(t *testing.T) {
+	conn := newTestDB(t)
+	createUser(t, conn, "alice", "alice@example.com")
+
+	id := models.ExternalIdentity{Provider: "mock", Subject: "alice2-sub", Email: "alice2@example.com", EmailVerified: true, Username: "alice"}
+	user, created, err := models.SignInWithIdentity(conn, id)
+	if err != nil {
+		t.Fatalf("SignInWithIdentity: %v", err)
+	}
+	if !created || user.Username != "alice2" || !user.Verified {
+		t.Errorf("user = %q (created %v, verified %v), want new verified user alice2", user.Username, created, user.Verified)
+	}
+}
+
+func TestSignInWithIdentityRespectsRegistrationMode(t *testing.T) {
+	for _, mode := range []string{models.RegistrationClosed, models.RegistrationInvite} {
+		t.Run(mode, func(t *testing.T) {
+			conn := newTestDB(t)
+			if err := models.SetRegistrationMode(conn, mode); err != nil {
+				t.Fatalf("SetRegistrationMode: %v", err)
+			}
+
+			id := models.ExternalIdentity{Provider: "mock", Subject: "new-sub", Email: "new@example.com", EmailVerified: true}
+			_, created, err := models.SignInWithIdentity(conn, id)
+			if !models.IsValidationError(err) || created {
+				t.Fatalf("SignInWithIdentity = created %v, err %v; want a validation error", created, err)
+			}
+			var users int64
+			conn.Model(&models.User{}).Count(&users)
+			if users != 0 || identityCount(t, conn) != 0 {
+				t.Errorf("%d users created, want 0", users)
+			}
+		})
+	}
+}
+
+func TestSignInWithIdentityAwaitsApproval(t *testing.T) {
+	conn := newTestDB(t)
+	if err := models.SetRegistrationMode(conn, models.RegistrationApproval); err != nil {
+		t.Fatalf("SetRegistrationMode: %v", err)
+	}
+
+	id := models.ExternalIdentity{Provider: "mock", Subject: "new-sub", Email: "new@example.com", EmailVerified: true}
+	user, created, err := models.SignInWithIdentity(conn, id)
+	if err != nil || !created {
+		t.Fatalf("SignInWithIdentity = created %v, err %v", created, err)
+	}
+	if !user.ApprovalPending {
+		t.Error("user created in approval mode is not waiting for approval")
+	}
+}
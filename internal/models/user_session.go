@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionTouchInterval is how often a session's LastSeenAt is updated while it is in use.
+const SessionTouchInterval = time.Minute
+
+// UserSession describes one logged-in browser session, so its owner can see where they are
+// signed in and sign other devices out. The session data itself lives in the session store under SessionID.
+type UserSession struct {
+	ID         uint      `gorm:"primaryKey"`
+	SessionID  string    `gorm:"uniqueIndex;not null"`
+	UserID     uint      `gorm:"not null;index"`
+	IP         string    `gorm:"not null"`
+	UserAgent  string    `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"not null"`
+	LastSeenAt time.Time `gorm:"not null"`
+}
+
+// FindUserSession looks up the record for sessionID.
+func FindUserSession(db *gorm.DB, sessionID string) (*UserSession, error) {
+	var s UserSession
+	if err := db.Where("session_id = ?", sessionID).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Touch records that the session was just used. Writes are throttled to one per SessionTouchInterval.
+func (s *UserSession) Touch(db *gorm.DB, ip string) error {
+	if time.Since(s.LastSeenAt) < SessionTouchInterval && s.IP == ip {
+		return nil
+	}
+	s.LastSeenAt = time.Now()
+	s.IP = ip
+	return db.Model(s).UpdateColumns(map[string]interface{}{"last_seen_at": s.LastSeenAt, "ip": ip}).Error
+}
+
+// ActiveUserSessions lists userID's sessions seen within lifetime, most recently used first.
+func ActiveUserSessions(db *gorm.DB, userID uint, lifetime time.Duration) ([]UserSession, error) {
+	var sessions []UserSession
+	err := db.Where("user_id = ? AND last_seen_at > ?", userID, time.Now().Add(-lifetime)).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeUserSession deletes one of userID's session records and returns its session ID, which the
+// caller must also remove from the session store. A session that does not exist, or belongs to
+// someone else, is reported as gorm.ErrRecordNotFound.
+func RevokeUserSession(db *gorm.DB, userID, id uint) (string, error) {
+	var s UserSession
+	if err := db.Where("user_id = ?", userID).First(&s, id).Error; err != nil {
+		return "", err
+	}
+	if err := db.Delete(&s).Error; err != nil {
+		return "", err
+	}
+	return s.SessionID, nil
+}
+
+// RevokeOtherUserSessions deletes every session record of userID except keepSessionID and returns
+// the deleted session IDs, which the caller must also remove from the session store.
+func RevokeOtherUserSessions(db *gorm.DB, userID uint, keepSessionID string) ([]string, error) {
+	var ids []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		others := tx.Model(&UserSession{}).Where("user_id = ? AND session_id <> ?", userID, keepSessionID)
+		if err := others.Session(&gorm.Session{}).Pluck("session_id", &ids).Error; err != nil {
+			return err
+		}
+		return others.Session(&gorm.Session{}).Delete(&UserSession{}).Error
+	})
+	return ids, err
+}
+
+// DeleteUserSession removes the record for sessionID, e.g. when its owner logs out.
+func DeleteUserSession(db *gorm.DB, sessionID string) error {
+	return db.Where("session_id = ?", sessionID).Delete(&UserSession{}).Error
+}
+
+// Device summarizes the session's user agent as "Browser on OS", e.g. "Firefox on Linux".
+func (s UserSession) Device() string {
+	ua := s.UserAgent
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	platform := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Account statuses, used as User.Status.
+const (
+	StatusActive       = "active"       // A normal account
+	StatusSuspended    = "suspended"    // Signed out and hidden until User.SuspendedUntil
+	StatusBanned       = "banned"       // Signed out and hidden for good
+	StatusShadowbanned = "shadowbanned" // Can use Void as normal, but nobody else sees their content
+)
+
+// UserStatuses lists every account status.
+var UserStatuses = []string{StatusActive, StatusSuspended, StatusBanned, StatusShadowbanned}
+
+// CurrentStatus returns the user's status, treating a suspension that has run out as active.
+func (u User) CurrentStatus() string {
+	switch {
+	case u.Status == "": // Empty when the status column was not loaded
+		return StatusActive
+	case u.Status == StatusSuspended && (u.SuspendedUntil == nil || !u.SuspendedUntil.After(time.Now())):
+		return StatusActive
+	}
+	return u.Status
+}
+
+// Suspended reports whether the user is currently suspended.
+func (u User) Suspended() bool {
+	return u.CurrentStatus() == StatusSuspended
+}
+
+// Banned reports whether the user is banned.
+func (u User) Banned() bool {
+	return u.CurrentStatus() == StatusBanned
+}
+
+// Restricted reports whether the user is suspended or banned, and so may not sign in.
+func (u User) Restricted() bool {
+	return u.Suspended() || u.Banned()
+}
+
+// HiddenAuthorIDs returns a subquery selecting the users whose shouts and echoes viewerID must
+// not see: banned and currently suspended users, shadowbanned users other than the viewer, and
+// users the viewer has blocked or been blocked by. Pass zero for anonymous viewers, or for things
+// nobody else should see, such as notifications.
+func HiddenAuthorIDs(db *gorm.DB, viewerID uint) *gorm.DB {
+	return db.Model(&User{}).Select("id").
+		Where("status = ? OR (status = ? AND suspended_until > ?) OR (status = ? AND id != ?) OR id IN (?)",
+			StatusBanned, StatusSuspended, time.Now(), StatusShadowbanned, viewerID, BlockedUserIDs(db, viewerID))
+}
+
+// IsHiddenAuthor reports whether other users must not see userID's shouts and echoes.
+func IsHiddenAuthor(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&User{}).Where("id IN (?)", HiddenAuthorIDs(db, 0)).Where("id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
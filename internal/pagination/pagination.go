@@ -0,0 +1,149 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxPageSize is the largest page size a client may request with the "limit" query parameter.
+	MaxPageSize = 100
+)
+
+// DefaultPageSize is the number of items per page when the request does not specify a limit.
+var DefaultPageSize = 20
+
+// Cursor identifies a position in a feed ordered by created_at and then id, newest first.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode serializes the cursor into an opaque, URL-safe string.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Cursor.Encode.
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	i, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	return Cursor{CreatedAt: time.Unix(0, n), ID: uint(i)}, nil
+}
+
+// Request describes which page of a feed to load.
+// Before asks for items older than the cursor, After for items newer than it; neither means the newest page.
+type Request struct {
+	Before *Cursor
+	After  *Cursor
+	Limit  int
+}
+
+// FromRequest reads the "before", "after" and "limit" query parameters.
+func FromRequest(c *fiber.Ctx) (Request, error) {
+	req := Request{Limit: DefaultPageSize}
+
+	if limit := c.QueryInt("limit", DefaultPageSize); limit > 0 {
+		req.Limit = min(limit, MaxPageSize)
+	}
+	if before := c.Query("before"); before != "" {
+		cursor, err := Decode(before)
+		if err != nil {
+			return req, err
+		}
+		req.Before = &cursor
+	}
+	if after := c.Query("after"); after != "" {
+		cursor, err := Decode(after)
+		if err != nil {
+			return req, err
+		}
+		req.After = &cursor
+	}
+	return req, nil
+}
+
+// Page holds the links to the neighbouring pages. Empty strings mean there is no such page.
+type Page struct {
+	Older string // Query string for the next older page, e.g. "?before=..."
+	Newer string // Query string for the next newer page, e.g. "?after=..."
+}
+
+// Find loads one page of query into a slice, newest first, using keyset pagination on
+// (created_at, id). cursorOf extracts the cursor of an item so neighbouring pages can be linked.
+// The query must select from a single table whose created_at and id columns are unambiguous.
+func Find[T any](query *gorm.DB, req Request, cursorOf func(T) Cursor) ([]T, Page, error) {
+	var items []T
+	q := query.Limit(req.Limit + 1)
+	switch {
+	case req.After != nil:
+		q = q.Where("created_at > ? OR (created_at = ? AND id > ?)", req.After.CreatedAt, req.After.CreatedAt, req.After.ID).
+			Order("created_at asc, id asc")
+	case req.Before != nil:
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", req.Before.CreatedAt, req.Before.CreatedAt, req.Before.ID).
+			Order("created_at desc, id desc")
+	default:
+		q = q.Order("created_at desc, id desc")
+	}
+	if err := q.Find(&items).Error; err != nil {
+		return nil, Page{}, err
+	}
+
+	more := len(items) > req.Limit
+	if more {
+		items = items[:req.Limit]
+	}
+	if req.After != nil {
+		// Newer pages are fetched oldest first; flip them back to newest first.
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	var page Page
+	if len(items) == 0 {
+		return items, page, nil
+	}
+	hasOlder := more || req.After != nil
+	hasNewer := req.Before != nil || (req.After != nil && more)
+	if hasOlder {
+		page.Older = req.link("before", cursorOf(items[len(items)-1]))
+	}
+	if hasNewer {
+		page.Newer = req.link("after", cursorOf(items[0]))
+	}
+	return items, page, nil
+}
+
+// link builds the query string for a neighbouring page, preserving a non-default limit.
+func (r Request) link(param string, cursor Cursor) string {
+	values := url.Values{}
+	values.Set(param, cursor.Encode())
+	if r.Limit != DefaultPageSize {
+		values.Set("limit", strconv.Itoa(r.Limit))
+	}
+	return "?" + values.Encode()
+}
@@ -0,0 +1,121 @@
+// Package policy decides what each user may do. Handlers and templates ask Can before acting or
+// showing a control, so ownership and role rules live in one place.
+package policy
+
+import (
+	"Void/internal/models"
+)
+
+// Action is something a user may be allowed to do, usually to a resource.
+type Action string
+
+// Actions on content.
+const (
+	ManageShout      Action = "shout.manage"      // Open a shout's author page and echo from it
+	EditShout        Action = "shout.edit"        // Change a shout's content
+	DeleteShout      Action = "shout.delete"      // Remove a shout
+	DeleteEcho       Action = "echo.delete"       // Remove an echo
+	ReadNotification Action = "notification.read" // View a notification or mark it read
+	Report           Action = "report"            // Report a shout, echo or user to the moderators
+)
+
+// Moderation actions.
+const (
+	RestrictUser Action = "user.restrict" // Suspend, ban or shadowban a user, lift that, or lock their password
+)
+
+// Instance-wide actions, which take no resource.
+const (
+	ManageRegistrations    Action = "registrations.manage" // Change the registration mode and approve accounts
+	CreateUnlimitedInvites Action = "invites.unlimited"    // Create invites without the per-user limits
+)
+
+// Can reports whether actor may perform action on resource, which is a model value or pointer such
+// as models.Shout or *models.Shout, or nil for instance-wide actions. Anonymous actors (ID zero),
+// unknown actions and resources of the wrong type are denied.
+func Can(actor models.User, action Action, resource interface{}) bool {
+	if actor.ID == 0 {
+		return false
+	}
+
+	switch action {
+	case ManageShout, EditShout:
+		shout, ok := asShout(resource)
+		return ok && shout.UserID == actor.ID
+	case DeleteShout:
+		shout, ok := asShout(resource)
+		return ok && (shout.UserID == actor.ID || actor.HasRole(models.RoleModerator))
+	case DeleteEcho:
+		echo, ok := asEcho(resource)
+		// Echoes from before authors were tracked have no author, so only moderators can delete them.
+		return ok && (echo.UserID != 0 && echo.UserID == actor.ID || actor.HasRole(models.RoleModerator))
+	case ReadNotification:
+		notif, ok := asNotification(resource)
+		return ok && notif.UserID == actor.ID
+	case Report:
+		// Anyone may report anything except themselves and their own content.
+		if shout, ok := asShout(resource); ok {
+			return shout.UserID != actor.ID
+		}
+		if echo, ok := asEcho(resource); ok {
+			return echo.UserID != 0 && echo.UserID != actor.ID
+		}
+		user, ok := asUser(resource)
+		return ok && user.ID != actor.ID
+	case RestrictUser:
+		// Moderators can only restrict people less privileged than themselves.
+		user, ok := asUser(resource)
+		return ok && actor.HasRole(models.RoleModerator) && actor.Outranks(user)
+	case ManageRegistrations, CreateUnlimitedInvites:
+		return actor.HasRole(models.RoleAdmin)
+	}
+	return false
+}
+
+func asShout(resource interface{}) (models.Shout, bool) {
+	switch r := resource.(type) {
+	case models.Shout:
+		return r, true
+	case *models.Shout:
+		if r != nil {
+			return *r, true
+		}
+	}
+	return models.Shout{}, false
+}
+
+func asEcho(resource interface{}) (models.Echo, bool) {
+	switch r := resource.(type) {
+	case models.Echo:
+		return r, true
+	case *models.Echo:
+		if r != nil {
+			return *r, true
+		}
+	}
+	return models.Echo{}, false
+}
+
+func asNotification(resource interface{}) (models.Notification, bool) {
+	switch r := resource.(type) {
+	case models.Notification:
+		return r, true
+	case *models.Notification:
+		if r != nil {
+			return *r, true
+		}
+	}
+	return models.Notification{}, false
+}
+
+func asUser(resource interface{}) (models.User, bool) {
+	switch r := resource.(type) {
+	case models.User:
+		return r, true
+	case *models.User:
+		if r != nil {
+			return *r, true
+		}
+	}
+	return models.User{}, false
+}
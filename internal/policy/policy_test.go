@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"Void/internal/models"
+)
+
+// user returns a user with the given ID and role.
+func user(id uint, role string) models.User {
+	return models.User{Model: gorm.Model{ID: id}, Role: role}
+}
+
+func TestCan(t *testing.T) {
+	var (
+		anonymous  = models.User{}
+		owner      = user(1, models.RoleUser)
+		other      = user(2, models.RoleUser)
+		moderator  = user(3, models.RoleModerator)
+		admin      = user(4, models.RoleAdmin)
+		moderator2 = user(5, models.RoleModerator)
+	)
+	shout := models.Shout{Model: gorm.Model{ID: 10}, UserID: owner.ID}
+	echo := models.Echo{Model: gorm.Model{ID: 20}, UserID: owner.ID}
+	orphanEcho := models.Echo{Model: gorm.Model{ID: 21}}
+
+	tests := []struct {
+		name     string
+		actor    models.User
+		action   Action
+		resource interface{}
+		want     bool
+	}{
+		{"owner edits shout", owner, EditShout, shout, true},
+		{"other edits shout", other, EditShout, &shout, false},
+		{"moderator edits shout", moderator, EditShout, shout, false},
+
+		{"owner deletes shout", owner, DeleteShout, shout, true},
+		{"other deletes shout", other, DeleteShout, shout, false},
+		{"moderator deletes shout", moderator, DeleteShout, &shout, true},
+		{"admin deletes shout", admin, DeleteShout, shout, true},
+		{"anonymous deletes shout", anonymous, DeleteShout, models.Shout{}, false},
+
+		{"owner deletes echo", owner, DeleteEcho, echo, true},
+		{"other deletes echo", other, DeleteEcho, &echo, false},
+		{"moderator deletes echo", moderator, DeleteEcho, echo, true},
+		{"user deletes echo without author", other, DeleteEcho, orphanEcho, false},
+		{"anonymous deletes echo without author", anonymous, DeleteEcho, orphanEcho, false},
+		{"moderator deletes echo without author", moderator, DeleteEcho, orphanEcho, true},
+
+		{"moderator restricts user", moderator, RestrictUser, other, true},
+		{"moderator restricts moderator", moderator, RestrictUser, moderator2, false},
+		{"moderator restricts admin", moderator, RestrictUser, &admin, false},
+		{"moderator restricts self", moderator, RestrictUser, moderator, false},
+		{"admin restricts moderator", admin, RestrictUser, moderator, true},
+		{"user restricts user", owner, RestrictUser, other, false},
+
+		{"wrong resource type", owner, DeleteShout, echo, false},
+		{"nil pointer", moderator, DeleteShout, (*models.Shout)(nil), false},
+		{"unknown action", admin, Action("everything"), nil, false},
+		{"admin manages registrations", admin, ManageRegistrations, nil, true},
+		{"moderator manages registrations", moderator, ManageRegistrations, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Can(tt.actor, tt.action, tt.resource); got != tt.want {
+				t.Errorf("Can(%s, %s) = %v, want %v", tt.actor.Role, tt.action, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package feed
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"Void/internal/events"
+)
+
+// clientBuffer is how many undelivered items a connection may queue. A connection that
+// falls further behind is disconnected so one slow reader cannot hold up the rest.
+const clientBuffer = 32
+
+// Item kinds broadcast to the live echo chamber.
+const (
+	KindShout = "shout"
+	KindEcho  = "echo"
+)
+
+// Item is a newly created shout or echo pushed to live echo chamber connections.
+type Item struct {
+	Kind      string    `json:"kind"`
+	ShoutID   uint      `json:"shout_id"`
+	EchoID    uint      `json:"echo_id,omitempty"`
+	Content   string    `json:"content"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Client is a single live connection's outgoing queue.
+type Client struct {
+	viewerID  uint
+	send      chan []byte
+	closeOnce sync.Once
+}
+
+// Send returns the channel of encoded items to write to the connection.
+// It is closed when the client is unregistered, including when it falls too far behind.
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+// Hub broadcasts items to every live echo chamber connection within this process.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+}
+
+// DefaultHub is the hub shared by the event consumer and the WebSocket handler.
+var DefaultHub = NewHub()
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*Client]struct{})}
+}
+
+// Register adds a new live connection watched by viewerID, or zero for a logged-out visitor.
+func (h *Hub) Register(viewerID uint) *Client {
+	c := &Client{viewerID: viewerID, send: make(chan []byte, clientBuffer)}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// Unregister removes the connection from the hub and closes its queue. It is safe to call more than once.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	c.closeOnce.Do(func() { close(c.send) })
+}
+
+// Broadcast queues item for every connection, disconnecting any whose queue is full. Connections
+// whose viewer hiddenFrom reports true are skipped; a nil hiddenFrom delivers to everyone.
+func (h *Hub) Broadcast(item Item, hiddenFrom func(viewerID uint) bool) {
+	msg, err := json.Marshal(item)
+	if err != nil {
+		log.Printf("Failed to marshal feed item: %v", err)
+		return
+	}
+
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.clients {
+		if hiddenFrom != nil && hiddenFrom(c.viewerID) {
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		log.Println("Disconnecting slow echo chamber client")
+		h.Unregister(c)
+	}
+}
+
+// ShoutItem converts a shout event into a feed Item.
+func ShoutItem(event events.ShoutEvent) Item {
+	return Item{
+		Kind:      KindShout,
+		ShoutID:   event.GetShoutID(),
+		Content:   event.GetContent(),
+		Username:  event.GetUsername(),
+		Avatar:    avatarOf(event),
+		CreatedAt: time.Now(),
+	}
+}
+
+// EchoItem converts an echo event into a feed Item.
+func EchoItem(event events.EchoEvent) Item {
+	return Item{
+		Kind:      KindEcho,
+		ShoutID:   event.GetShoutID(),
+		EchoID:    event.GetEchoID(),
+		Content:   event.GetContent(),
+		Username:  event.GetUsername(),
+		Avatar:    avatarOf(event),
+		CreatedAt: time.Now(),
+	}
+}
+
+// avatarOf returns the event's avatar when the concrete event type carries one.
+func avatarOf(event any) string {
+	if a, ok := event.(interface{ GetAvatar() string }); ok {
+		return a.GetAvatar()
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package feed
+
+import "testing"
+
+func TestBroadcastSkipsHiddenViewers(t *testing.T) {
+	hub := NewHub()
+	alice := hub.Register(1)
+	bob := hub.Register(2)
+	visitor := hub.Register(0)
+
+	hub.Broadcast(Item{Kind: KindShout, ShoutID: 7}, func(viewerID uint) bool { return viewerID == 2 })
+
+	for name, c := range map[string]*Client{"alice": alice, "visitor": visitor} {
+		select {
+		case <-c.Send():
+		default:
+			t.Errorf("%s did not receive the item", name)
+		}
+	}
+	select {
+	case msg := <-bob.Send():
+		t.Errorf("bob received %s, want nothing", msg)
+	default:
+	}
+}
+
+func TestBroadcastDisconnectsSlowClients(t *testing.T) {
+	hub := NewHub()
+	c := hub.Register(1)
+	for i := 0; i <= clientBuffer; i++ {
+		hub.Broadcast(Item{Kind: KindShout, ShoutID: uint(i)}, nil)
+	}
+
+	received := 0
+	for range c.Send() {
+		received++
+	}
+	if received != clientBuffer {
+		t.Errorf("received %d items before disconnect, want %d", received, clientBuffer)
+	}
+}
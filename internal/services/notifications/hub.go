@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"sync"
+	"time"
+
+	"Void/internal/models"
+)
+
+// subscriberBuffer is how many undelivered messages a subscriber may queue before new ones are dropped.
+const subscriberBuffer = 16
+
+// Message is a notification pushed to a live subscriber, along with the recipient's unread count.
+type Message struct {
+	ID             uint      `json:"id"`
+	Type           string    `json:"type"`
+	Message        string    `json:"message"`
+	AuthorUsername string    `json:"author_username"`
+	AuthorAvatar   string    `json:"author_avatar"`
+	ShoutID        uint      `json:"shout_id"`
+	EchoID         uint      `json:"echo_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UnreadCount    int64     `json:"unread_count"`
+}
+
+// Hub fans notifications out to the live connections of each user within this process.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan Message]struct{}
+}
+
+// DefaultHub is the hub shared by the notification consumer and the stream handler.
+var DefaultHub = NewHub()
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uint]map[chan Message]struct{})}
+}
+
+// Subscribe registers a new live connection for userID. The returned function must be
+// called when the connection closes to release it.
+func (h *Hub) Subscribe(userID uint) (<-chan Message, func()) {
+	ch := make(chan Message, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Message]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[userID], ch)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// HasSubscribers reports whether userID has at least one live connection.
+func (h *Hub) HasSubscribers(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[userID]) > 0
+}
+
+// Publish delivers msg to every live connection of userID. Slow connections whose
+// buffers are full miss the message rather than blocking the publisher.
+func (h *Hub) Publish(userID uint, msg Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// NewMessage builds a Message from a stored notification and the recipient's unread count.
+func NewMessage(n models.Notification, unread int64) Message {
+	return Message{
+		ID:             n.ID,
+		Type:           n.Type,
+		Message:        n.Message,
+		AuthorUsername: n.AuthorUsername,
+		AuthorAvatar:   n.AuthorAvatar,
+		ShoutID:        n.ShoutID,
+		EchoID:         n.EchoID,
+		CreatedAt:      n.CreatedAt,
+		UnreadCount:    unread,
+	}
+}
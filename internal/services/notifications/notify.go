@@ -8,14 +8,33 @@ import (
 	"Void/internal/models"
 )
 
-// SendNewShoutNotifications sends a notification to every user except the shout's author.
+// batchSize is the number of notification rows written per INSERT statement.
+const batchSize = 100
+
+// SendNewShoutNotifications notifies the author's followers who opted in to every post,
+// plus any users mentioned in the shout. The author is never notified of their own shout, nobody
+// is notified of shouts by shadowbanned or otherwise hidden authors, and users on either side of
+// a block with the author are left out.
 // internal/services/notifications/notify.go
 func SendNewShoutNotifications(event events.ShoutEvent) {
 	log.Printf("Creating notifications for shout ID: %d", event.GetShoutID())
-	var recipients []models.User
 
+	if hiddenAuthor(event.GetUserID()) {
+		return
+	}
+
+	mentioned, err := mentionedUserIDs(event.GetUserID(), event.GetShoutID(), 0)
+	if err != nil {
+		log.Printf("Error fetching mentioned users: %v", err)
+		return
+	}
+
+	var recipients []models.User
 	if err := db.DB.
+		Where(db.DB.Where("id IN (?)", models.NotifiedFollowerIDs(db.DB, event.GetUserID())).
+			Or("id IN (?)", models.MentionedUserIDs(db.DB, event.GetShoutID(), 0))).
 		Where("id != ?", event.GetUserID()).
+		Where("id NOT IN (?)", models.BlockedUserIDs(db.DB, event.GetUserID())).
 		Find(&recipients).Error; err != nil {
 		log.Printf("Error fetching recipients: %v", err)
 		return
@@ -23,20 +42,155 @@ func SendNewShoutNotifications(event events.ShoutEvent) {
 
 	log.Printf("Found %d recipients", len(recipients))
 
+	notifications := make([]models.Notification, 0, len(recipients))
 	for _, user := range recipients {
-		notification := models.Notification{
+		notificationType := models.NotificationNewShout
+		if mentioned[user.ID] {
+			notificationType = models.NotificationMention
+		}
+		notifications = append(notifications, models.Notification{
 			UserID:         user.ID,
+			Type:           notificationType,
 			Message:        truncate(event.GetContent(), 50),
 			AuthorUsername: event.GetUsername(),
 			AuthorAvatar:   event.(interface{ GetAvatar() string }).GetAvatar(), // type assertion if needed
 			ShoutID:        event.GetShoutID(),
+		})
+	}
+	createNotifications(notifications)
+}
+
+// SendEchoNotification notifies the author of a shout that someone echoed it, and notifies
+// any users mentioned in the echo. Nobody is notified of their own echo, of echoes by
+// shadowbanned or otherwise hidden authors, or of echoes by a user they have a block with.
+func SendEchoNotification(event events.EchoEvent) {
+	if hiddenAuthor(event.GetUserID()) {
+		return
+	}
+
+	base := models.Notification{
+		Message:        truncate(event.GetContent(), 50),
+		AuthorUsername: event.GetUsername(),
+		AuthorAvatar:   event.(interface{ GetAvatar() string }).GetAvatar(),
+		ShoutID:        event.GetShoutID(),
+		EchoID:         event.GetEchoID(),
+	}
+
+	var notifications []models.Notification
+	if event.GetShoutAuthorID() != 0 && event.GetShoutAuthorID() != event.GetUserID() &&
+		!models.IsBlocked(db.DB, event.GetShoutAuthorID(), event.GetUserID()) {
+		notification := base
+		notification.UserID = event.GetShoutAuthorID()
+		notification.Type = models.NotificationEcho
+		notifications = append(notifications, notification)
+	}
+
+	mentioned, err := mentionedUserIDs(event.GetUserID(), event.GetShoutID(), event.GetEchoID())
+	if err != nil {
+		log.Printf("Error fetching mentioned users: %v", err)
+	}
+	for userID := range mentioned {
+		// The shout's author already hears about the echo itself.
+		if userID == event.GetUserID() || userID == event.GetShoutAuthorID() {
+			continue
 		}
-		if err := db.DB.Create(&notification).Error; err != nil {
-			log.Printf("Error creating notification for user %d: %v", user.ID, err)
-		} else {
-			log.Printf("Created notification ID %d for user %d", notification.ID, user.ID)
-		}
+		notification := base
+		notification.UserID = userID
+		notification.Type = models.NotificationMention
+		notifications = append(notifications, notification)
+	}
+	createNotifications(notifications)
+}
+
+// hiddenAuthor reports whether other users must not see userID's content, treating lookup
+// errors as hidden so a failure cannot leak a shadowbanned user's posts.
+func hiddenAuthor(userID uint) bool {
+	hidden, err := models.IsHiddenAuthor(db.DB, userID)
+	if err != nil {
+		log.Printf("Error checking status of user %d: %v", userID, err)
+		return true
+	}
+	return hidden
+}
+
+// mentionedUserIDs returns the set of users mentioned by authorID in a shout (echoID zero) or in one
+// of its echoes, leaving out users on either side of a block with the author.
+func mentionedUserIDs(authorID, shoutID, echoID uint) (map[uint]bool, error) {
+	var ids []uint
+	if err := models.MentionedUserIDs(db.DB, shoutID, echoID).
+		Where("user_id NOT IN (?)", models.BlockedUserIDs(db.DB, authorID)).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// createNotifications writes the notifications in batched inserts and pushes them to recipients
+// who are connected to the live notification stream.
+func createNotifications(notifications []models.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	if err := db.DB.CreateInBatches(&notifications, batchSize).Error; err != nil {
+		log.Printf("Error creating notifications: %v", err)
+		return
+	}
+	log.Printf("Created %d notifications", len(notifications))
+
+	for _, notification := range notifications {
+		push(notification)
+	}
+}
+
+// push delivers a stored notification to the recipient's live connections, if any.
+func push(notification models.Notification) {
+	if !DefaultHub.HasSubscribers(notification.UserID) {
+		return
+	}
+	var unread int64
+	db.DB.Model(&models.Notification{}).Where("user_id = ? AND read = ?", notification.UserID, false).Count(&unread)
+	DefaultHub.Publish(notification.UserID, NewMessage(notification, unread))
+}
+
+// SendFollowNotification notifies followeeID that follower started following them.
+func SendFollowNotification(follower models.User, followeeID uint) {
+	notification := models.Notification{
+		UserID:         followeeID,
+		Type:           models.NotificationFollow,
+		Message:        follower.Username + " started following you",
+		AuthorUsername: follower.Username,
+		AuthorAvatar:   follower.Avatar,
+	}
+	if err := db.DB.Create(&notification).Error; err != nil {
+		log.Printf("Error creating follow notification for user %d: %v", followeeID, err)
+		return
+	}
+	push(notification)
+}
+
+// reportOutcomes describes what happened to a resolved report, keyed by the moderator's action.
+var reportOutcomes = map[string]string{
+	models.ReportActionHide:    "A moderator reviewed it and removed the content.",
+	models.ReportActionSuspend: "A moderator reviewed it and suspended the account.",
+	models.ReportActionDismiss: "A moderator reviewed it and found it doesn't break the rules.",
+}
+
+// SendReportResolvedNotifications tells each reporter how a moderator resolved their report.
+func SendReportResolvedNotifications(reports []models.Report) {
+	notifications := make([]models.Notification, 0, len(reports))
+	for _, report := range reports {
+		notifications = append(notifications, models.Notification{
+			UserID: report.ReporterID,
+			Type:   models.NotificationReport,
+			Message: "Thanks for your report (" + report.ReasonLabel() + "). " +
+				reportOutcomes[report.Resolution],
+		})
 	}
+	createNotifications(notifications)
 }
 
 // truncate shortens a string to a specified length and appends "..." if truncation occurs.
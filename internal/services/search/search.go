@@ -0,0 +1,267 @@
+package search
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"Void/internal/db"
+	"Void/internal/models"
+)
+
+// Markers wrapped around matched terms in snippets. They are control characters so they
+// cannot collide with user content and survive HTML escaping unchanged.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// ErrDisabled is returned when the FTS5 indexes are not available.
+var ErrDisabled = errors.New("search is not available on this server")
+
+// Query is a parsed search query.
+type Query struct {
+	Terms []string // Words and "quoted phrases" to match, all of which must be present
+	From  string   // Restrict shouts and echoes to this author (from:username)
+	Tags  []string // Restrict shouts to these hashtags (#tag)
+}
+
+// Empty reports whether the query has nothing to search for.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && q.From == "" && len(q.Tags) == 0
+}
+
+// match builds an FTS5 MATCH expression, quoting every term so user input cannot inject FTS syntax.
+func (q Query) match() string {
+	quoted := make([]string, len(q.Terms))
+	for i, term := range q.Terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Parse splits raw search input into terms, "quoted phrases", from:username and #tag filters.
+func Parse(raw string) Query {
+	var q Query
+	for _, token := range tokenize(raw) {
+		switch {
+		case token.phrase:
+			q.Terms = append(q.Terms, token.text)
+		case strings.HasPrefix(strings.ToLower(token.text), "from:") && len(token.text) > len("from:"):
+			q.From = strings.TrimPrefix(token.text[len("from:"):], "@")
+		case strings.HasPrefix(token.text, "#") && len(token.text) > 1:
+			q.Tags = append(q.Tags, strings.ToLower(token.text[1:]))
+		default:
+			q.Terms = append(q.Terms, token.text)
+		}
+	}
+	return q
+}
+
+type token struct {
+	text   string
+	phrase bool
+}
+
+// tokenize splits input on whitespace, keeping double-quoted phrases together.
+func tokenize(raw string) []token {
+	var tokens []token
+	var current strings.Builder
+	inPhrase := false
+
+	flush := func(phrase bool) {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			tokens = append(tokens, token{text: text, phrase: phrase})
+		}
+		current.Reset()
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			flush(inPhrase)
+			inPhrase = !inPhrase
+		case unicode.IsSpace(r) && !inPhrase:
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush(inPhrase)
+	return tokens
+}
+
+// ShoutResult is a shout matching a search.
+type ShoutResult struct {
+	ShoutID   uint
+	Username  string
+	Avatar    string
+	CreatedAt time.Time
+	Snippet   template.HTML
+}
+
+// EchoResult is an echo matching a search.
+type EchoResult struct {
+	EchoID    uint
+	ShoutID   uint
+	Username  string
+	Avatar    string
+	CreatedAt time.Time
+	Snippet   template.HTML
+}
+
+// UserResult is a user whose username or bio matches a search.
+type UserResult struct {
+	Username string
+	Avatar   string
+	Snippet  template.HTML
+}
+
+// Results holds the ranked matches of each kind.
+type Results struct {
+	Shouts []ShoutResult
+	Echoes []EchoResult
+	Users  []UserResult
+}
+
+// row is the shape every search query scans into before snippets are converted to HTML.
+type row struct {
+	ID        uint
+	ShoutID   uint
+	Username  string
+	Avatar    string
+	CreatedAt time.Time
+	Snippet   string
+}
+
+// Search runs q against the shout, echo and user indexes, returning up to limit results of each kind.
+// Text matches are ordered by relevance; filter-only queries are ordered newest first. Content and
+// users that viewerID may not see, such as suspended users, are left out; pass zero for anonymous viewers.
+func Search(q Query, viewerID uint, limit int) (Results, error) {
+	var results Results
+	if q.Empty() {
+		return results, nil
+	}
+	if !db.SearchEnabled {
+		return results, ErrDisabled
+	}
+
+	shouts, err := searchShouts(db.DB, q, viewerID, limit)
+	if err != nil {
+		return results, err
+	}
+	for _, r := range shouts {
+		results.Shouts = append(results.Shouts, ShoutResult{
+			ShoutID: r.ID, Username: r.Username, Avatar: r.Avatar, CreatedAt: r.CreatedAt, Snippet: highlight(r.Snippet),
+		})
+	}
+
+	// Echoes carry no hashtags, so a tag filter only ever matches shouts.
+	if len(q.Tags) == 0 {
+		echoes, err := searchEchoes(db.DB, q, viewerID, limit)
+		if err != nil {
+			return results, err
+		}
+		for _, r := range echoes {
+			results.Echoes = append(results.Echoes, EchoResult{
+				EchoID: r.ID, ShoutID: r.ShoutID, Username: r.Username, Avatar: r.Avatar, CreatedAt: r.CreatedAt, Snippet: highlight(r.Snippet),
+			})
+		}
+	}
+
+	// Users are only searched by free text; from: and #tag filters describe content, not people.
+	if len(q.Terms) > 0 && q.From == "" && len(q.Tags) == 0 {
+		users, err := searchUsers(db.DB, q, viewerID, limit)
+		if err != nil {
+			return results, err
+		}
+		for _, r := range users {
+			results.Users = append(results.Users, UserResult{
+				Username: r.Username, Avatar: r.Avatar, Snippet: highlight(r.Snippet),
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func searchShouts(db *gorm.DB, q Query, viewerID uint, limit int) ([]row, error) {
+	var rows []row
+	var query *gorm.DB
+	if len(q.Terms) > 0 {
+		query = db.Table("shouts_fts").
+			Select("shouts.id AS id, shouts.id AS shout_id, users.username, users.avatar, shouts.created_at, "+
+				"snippet(shouts_fts, 0, ?, ?, '…', 16) AS snippet", markStart, markEnd).
+			Joins("JOIN shouts ON shouts.id = shouts_fts.rowid").
+			Where("shouts_fts MATCH ?", q.match()).
+			Order("bm25(shouts_fts)")
+	} else {
+		query = db.Table("shouts").
+			Select("shouts.id AS id, shouts.id AS shout_id, users.username, users.avatar, shouts.created_at, shouts.content AS snippet").
+			Order("shouts.created_at DESC")
+	}
+	query = query.
+		Joins("JOIN users ON users.id = shouts.user_id").
+		Where("shouts.deleted_at IS NULL").
+		Where("shouts.user_id NOT IN (?)", models.HiddenAuthorIDs(db, viewerID))
+	if q.From != "" {
+		query = query.Where("users.username = ?", q.From)
+	}
+	for _, tag := range q.Tags {
+		query = query.Where("shouts.id IN (?)", models.TaggedShoutIDs(db, tag))
+	}
+	return rows, query.Limit(limit).Scan(&rows).Error
+}
+
+func searchEchoes(db *gorm.DB, q Query, viewerID uint, limit int) ([]row, error) {
+	var rows []row
+	var query *gorm.DB
+	if len(q.Terms) > 0 {
+		query = db.Table("echos_fts").
+			Select("echos.id AS id, echos.shout_id, users.username, users.avatar, echos.created_at, "+
+				"snippet(echos_fts, 0, ?, ?, '…', 16) AS snippet", markStart, markEnd).
+			Joins("JOIN echos ON echos.id = echos_fts.rowid").
+			Where("echos_fts MATCH ?", q.match()).
+			Order("bm25(echos_fts)")
+	} else {
+		query = db.Table("echos").
+			Select("echos.id AS id, echos.shout_id, users.username, users.avatar, echos.created_at, echos.content AS snippet").
+			Order("echos.created_at DESC")
+	}
+	query = query.
+		Joins("LEFT JOIN users ON users.id = echos.user_id").
+		Where("echos.deleted_at IS NULL").
+		Where("echos.user_id NOT IN (?)", models.HiddenAuthorIDs(db, viewerID))
+	if q.From != "" {
+		query = query.Where("users.username = ?", q.From)
+	}
+	return rows, query.Limit(limit).Scan(&rows).Error
+}
+
+func searchUsers(db *gorm.DB, q Query, viewerID uint, limit int) ([]row, error) {
+	var rows []row
+	// Column -1 lets FTS5 pick whichever of username or bio best matches for the snippet.
+	err := db.Table("users_fts").
+		Select("users.id AS id, users.username, users.avatar, "+
+			"snippet(users_fts, -1, ?, ?, '…', 16) AS snippet", markStart, markEnd).
+		Joins("JOIN users ON users.id = users_fts.rowid").
+		Where("users_fts MATCH ?", q.match()).
+		Where("users.deleted_at IS NULL").
+		Where("users.id NOT IN (?)", models.HiddenAuthorIDs(db, viewerID)).
+		Order("bm25(users_fts)").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// highlight HTML-escapes a snippet and turns the match markers into <mark> elements.
+func highlight(snippet string) template.HTML {
+	escaped := template.HTMLEscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, markEnd, "</mark>")
+	return template.HTML(escaped)
+}
@@ -0,0 +1,35 @@
+package views
+
+import (
+	"html/template"
+	"strings"
+
+	"Void/internal/models"
+)
+
+// RenderContent HTML-escapes shout or echo content and turns #tag tokens, and @username tokens
+// naming one of the mentioned users, into links. Handlers load mentioned with
+// models.MentionedUsernames; other @names are left as text. The patterns are applied to the
+// escaped text; the hashtag pattern skips escaped entities such as &#39;.
+func RenderContent(content string, mentioned map[string]bool) template.HTML {
+	escaped := template.HTMLEscapeString(content)
+	linked := models.MentionPattern.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := models.MentionPattern.FindStringSubmatch(match)
+		if !mentioned[parts[2]] {
+			return match
+		}
+		return parts[1] + `<a href="/users/` + parts[2] + `" class="mention">@` + parts[2] + `</a>`
+	})
+	linked = models.HashtagPattern.ReplaceAllStringFunc(linked, func(match string) string {
+		parts := models.HashtagPattern.FindStringSubmatch(match)
+		return parts[1] + `<a href="/tags/` + strings.ToLower(parts[2]) + `" class="hashtag">#` + parts[2] + `</a>`
+	})
+	return template.HTML(linked)
+}
+
+// CSRFField returns the hidden form field carrying the request's CSRF token. Templates receive the
+// token as .csrf; inside a range block, pass $.csrf.
+func CSRFField(token interface{}) template.HTML {
+	value, _ := token.(string)
+	return template.HTML(`<input type="hidden" name="_csrf" value="` + template.HTMLEscapeString(value) + `">`)
+}
@@ -0,0 +1,28 @@
+package views
+
+import "testing"
+
+func TestRenderContent(t *testing.T) {
+	mentioned := map[string]bool{"bob": true}
+	tests := []struct {
+		name, content, want string
+	}{
+		{"mentioned user", "hi @bob!", `hi <a href="/users/bob" class="mention">@bob</a>!`},
+		{"unmentioned name", "hi @nobody", "hi @nobody"},
+		{"email address", "mail bob@example.com", "mail bob@example.com"},
+		{"hashtag", "#Go time", `<a href="/tags/go" class="hashtag">#Go</a> time`},
+		{"escaped entity", "it's #fine", `it&#39;s <a href="/tags/fine" class="hashtag">#fine</a>`},
+		{"markup", "<b>@bob</b>", `&lt;b&gt;<a href="/users/bob" class="mention">@bob</a>&lt;/b&gt;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(RenderContent(tt.content, mentioned)); got != tt.want {
+				t.Errorf("RenderContent(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+
+	if got := string(RenderContent("hi @bob", nil)); got != "hi @bob" {
+		t.Errorf("RenderContent with no mentioned users = %q, want the mention left as text", got)
+	}
+}
@@ -0,0 +1,119 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application. It logs messages until Init is called.
+var Default Mailer = &LogMailer{}
+
+// Init configures Default from the environment. When MAIL_SMTP_ADDR (host:port) is set, mail is
+// sent over SMTP from MAIL_FROM, authenticating with MAIL_SMTP_USERNAME and MAIL_SMTP_PASSWORD if
+// given. Otherwise mail is written to files in MAIL_DIR, or to the log if that is unset too.
+func Init() {
+	if addr := os.Getenv("MAIL_SMTP_ADDR"); addr != "" {
+		Default = &SMTPMailer{
+			Addr:     addr,
+			From:     os.Getenv("MAIL_FROM"),
+			Username: os.Getenv("MAIL_SMTP_USERNAME"),
+			Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+		}
+		log.Printf("Sending mail through SMTP server %s", addr)
+		return
+	}
+	Default = &LogMailer{Dir: os.Getenv("MAIL_DIR")}
+	log.Println("MAIL_SMTP_ADDR is not set; mail will be logged instead of sent")
+}
+
+// Send sends msg with the Default mailer.
+func Send(msg Message) error {
+	return Default.Send(msg)
+}
+
+// SMTPMailer sends mail through an SMTP server, using STARTTLS when the server supports it.
+type SMTPMailer struct {
+	Addr     string // host:port of the server
+	From     string
+	Username string // Optional; enables PLAIN authentication
+	Password string
+}
+
+// Send delivers msg to the SMTP server.
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// LogMailer is a development stand-in that writes each message to a file in Dir, or to the log
+// when Dir is empty, instead of sending it.
+type LogMailer struct {
+	Dir string
+}
+
+// Send records msg.
+func (m *LogMailer) Send(msg Message) error {
+	raw := format("void@localhost", msg)
+	if m.Dir == "" {
+		log.Printf("Mail to %s:\n%s", msg.To, raw)
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), raw, 0o644)
+}
+
+// headerValue strips line breaks from header values so they cannot inject extra headers.
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sanitize makes an email address safe to use in a file name.
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, address)
+}
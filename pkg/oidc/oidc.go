@@ -0,0 +1,214 @@
+// oidc/oidc.go
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// requestTimeout bounds every request to a provider: discovery, key fetches and code exchanges.
+const requestTimeout = 10 * time.Second
+
+// ErrNonceMismatch is returned by Exchange when the ID token was not issued for this login attempt.
+var ErrNonceMismatch = errors.New("oidc: ID token nonce does not match")
+
+// Provider is an OpenID Connect identity provider users can sign in with. Its endpoints are
+// discovered from the issuer's /.well-known/openid-configuration the first time they are needed.
+type Provider struct {
+	Name         string // Identifies the provider in URLs, e.g. "google"
+	DisplayName  string // Shown on the login page, e.g. "Google"
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string // Requested in addition to "openid"
+
+	mu         sync.Mutex
+	discovered *gooidc.Provider
+}
+
+// Claims is the identity a provider asserted in a verified ID token.
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"-"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// Providers lists the configured providers in the order they are shown on the login page.
+var Providers []*Provider
+
+// Init configures Providers from the environment. OIDC_PROVIDERS is a comma-separated list of
+// provider names; each name's settings are read from OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, and optionally OIDC_<NAME>_DISPLAY_NAME and OIDC_<NAME>_SCOPES
+// (space-separated, "email profile" by default). Providers missing a required setting are skipped.
+func Init() {
+	Providers = nil
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		env := func(key string) string {
+			return os.Getenv("OIDC_" + strings.ToUpper(name) + "_" + key)
+		}
+		p := &Provider{
+			Name:         name,
+			DisplayName:  env("DISPLAY_NAME"),
+			Issuer:       env("ISSUER"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			Scopes:       strings.Fields(env("SCOPES")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("Skipping OIDC provider %q: OIDC_%s_ISSUER and OIDC_%s_CLIENT_ID are required", name, strings.ToUpper(name), strings.ToUpper(name))
+			continue
+		}
+		Register(p)
+	}
+}
+
+// Register adds p to Providers, filling in defaults for its display name and scopes.
+func Register(p *Provider) {
+	if p.DisplayName == "" {
+		p.DisplayName = p.Name
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"email", "profile"}
+	}
+	Providers = append(Providers, p)
+	log.Printf("OIDC sign-in enabled for %s (%s)", p.DisplayName, p.Issuer)
+}
+
+// Lookup returns the configured provider called name.
+func Lookup(name string) (*Provider, bool) {
+	for _, p := range Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// NewLoginState returns a fresh random state, nonce and PKCE code verifier for one login attempt.
+func NewLoginState() (state, nonce, verifier string, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+	if nonce, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+	return state, nonce, oauth2.GenerateVerifier(), nil
+}
+
+// AuthCodeURL returns the provider's authorization URL for a login attempt that will return to
+// redirectURL. The nonce is embedded in the ID token and the verifier's S256 challenge binds the
+// code to this attempt.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURL, state, nonce, verifier string) (string, error) {
+	config, _, err := p.config(ctx, redirectURL)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code for tokens and returns the claims of the verified ID
+// token. The token's signature, issuer, audience and expiry are checked, and its nonce must match.
+func (p *Provider) Exchange(ctx context.Context, redirectURL, code, nonce, verifier string) (*Claims, error) {
+	config, provider, err := p.config(ctx, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(p.client(ctx), requestTimeout)
+	defer cancel()
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: exchanging code: %w", err)
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	idToken, err := provider.Verifier(&gooidc.Config{ClientID: p.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: verifying ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims Claims
+	var verified struct {
+		EmailVerified interface{} `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if err := idToken.Claims(&verified); err != nil {
+		return nil, err
+	}
+	// Some providers send email_verified as the string "true".
+	claims.EmailVerified = verified.EmailVerified == true || verified.EmailVerified == "true"
+	claims.Subject = idToken.Subject
+	return &claims, nil
+}
+
+// config returns the OAuth2 configuration for p, discovering its endpoints if necessary.
+func (p *Provider) config(ctx context.Context, redirectURL string) (*oauth2.Config, *gooidc.Provider, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       append([]string{gooidc.ScopeOpenID}, p.Scopes...),
+	}, provider, nil
+}
+
+// discover fetches the provider's metadata once. A failed discovery is retried on the next login.
+func (p *Provider) discover(ctx context.Context) (*gooidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered != nil {
+		return p.discovered, nil
+	}
+
+	ctx, cancel := context.WithTimeout(p.client(ctx), requestTimeout)
+	defer cancel()
+	provider, err := gooidc.NewProvider(ctx, p.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovering %s: %w", p.Issuer, err)
+	}
+	p.discovered = provider
+	return provider, nil
+}
+
+// client makes the provider's HTTP requests time out instead of hanging.
+func (p *Provider) client(ctx context.Context) context.Context {
+	return gooidc.ClientContext(ctx, &http.Client{Timeout: requestTimeout})
+}
+
+// randomString returns 32 random bytes, base64url-encoded.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
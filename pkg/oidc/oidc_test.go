@@ -0,0 +1,146 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	"Void/pkg/oidc"
+	"Void/pkg/oidc/oidctest"
+)
+
+const redirectURL = "https://void.test/login/oidc/mock/callback"
+
+// signIn starts a login attempt against server, signs id in at the provider and returns the
+// authorization code and state from the callback, along with the attempt's nonce and verifier.
+func signIn(t *testing.T, server *oidctest.Server, provider *oidc.Provider, id oidctest.Identity) (code, state, nonce, verifier string) {
+	t.Helper()
+	state, nonce, verifier, err := oidc.NewLoginState()
+	if err != nil {
+		t.Fatalf("NewLoginState: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), redirectURL, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	server.SetIdentity(id)
+	callback, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state"), nonce, verifier
+}
+
+func TestAuthCodeURLSendsS256Challenge(t *testing.T) {
+	server := oidctest.NewServer("void", "secret")
+	defer server.Close()
+	provider := server.Provider("mock")
+
+	state, nonce, verifier, err := oidc.NewLoginState()
+	if err != nil {
+		t.Fatalf("NewLoginState: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), redirectURL, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", authURL, err)
+	}
+
+	q := u.Query()
+	sum := sha256.Sum256([]byte(verifier))
+	if got, want := q.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("code_challenge = %q, want %q", got, want)
+	}
+	if got := q.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	if q.Get("state") != state || q.Get("nonce") != nonce {
+		t.Errorf("state, nonce = %q, %q; want %q, %q", q.Get("state"), q.Get("nonce"), state, nonce)
+	}
+	if q.Get("redirect_uri") != redirectURL {
+		t.Errorf("redirect_uri = %q, want %q", q.Get("redirect_uri"), redirectURL)
+	}
+}
+
+func TestExchangeSendsVerifier(t *testing.T) {
+	server := oidctest.NewServer("void", "secret")
+	defer server.Close()
+	provider := server.Provider("mock")
+
+	code, _, nonce, verifier := signIn(t, server, provider, oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice",
+	})
+	claims, err := provider.Exchange(context.Background(), redirectURL, code, nonce, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got := server.LastVerifier(); got != verifier {
+		t.Errorf("provider received verifier %q, want %q", got, verifier)
+	}
+	if claims.Subject != "alice-sub" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.PreferredUsername != "alice" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	server := oidctest.NewServer("void", "secret")
+	defer server.Close()
+	provider := server.Provider("mock")
+
+	code, _, nonce, _ := signIn(t, server, provider, oidctest.Identity{Subject: "alice-sub", Email: "alice@example.com"})
+	_, _, otherVerifier, err := oidc.NewLoginState()
+	if err != nil {
+		t.Fatalf("NewLoginState: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), redirectURL, code, nonce, otherVerifier); err == nil {
+		t.Fatal("Exchange succeeded with a verifier that does not match the challenge")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	server := oidctest.NewServer("void", "secret")
+	defer server.Close()
+	provider := server.Provider("mock")
+
+	code, _, _, verifier := signIn(t, server, provider, oidctest.Identity{
+		Subject: "alice-sub", Email: "alice@example.com", Nonce: "issued-for-another-login",
+	})
+	_, err := provider.Exchange(context.Background(), redirectURL, code, "this-login", verifier)
+	if !errors.Is(err, oidc.ErrNonceMismatch) {
+		t.Fatalf("Exchange error = %v, want ErrNonceMismatch", err)
+	}
+}
+
+func TestExchangeEmailVerified(t *testing.T) {
+	server := oidctest.NewServer("void", "secret")
+	defer server.Close()
+	provider := server.Provider("mock")
+
+	for _, tt := range []struct {
+		claim interface{}
+		want  bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{"false", false},
+		{nil, false},
+	} {
+		code, _, nonce, verifier := signIn(t, server, provider, oidctest.Identity{
+			Subject: "alice-sub", Email: "alice@example.com", EmailVerified: tt.claim,
+		})
+		claims, err := provider.Exchange(context.Background(), redirectURL, code, nonce, verifier)
+		if err != nil {
+			t.Fatalf("email_verified %#v: Exchange: %v", tt.claim, err)
+		}
+		if claims.EmailVerified != tt.want {
+			t.Errorf("email_verified %#v: EmailVerified = %v, want %v", tt.claim, claims.EmailVerified, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package oidctest runs an in-process OpenID Connect provider for tests. It serves discovery,
+// JWKS, authorization and token endpoints, signs ID tokens with a key generated at startup, and
+// enforces the parts of the protocol Void relies on: single-use codes, matching redirect URIs and
+// PKCE S256 challenges.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+
+	"Void/pkg/oidc"
+)
+
+// keyID identifies the provider's signing key in its JWKS and ID token headers.
+const keyID = "oidctest"
+
+// Identity is the user the provider signs in at its authorization endpoint.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     interface{} // A bool, or a string for providers that send "true"; nil omits the claim
+	PreferredUsername string
+
+	// Nonce, if set, replaces the nonce from the authorization request in the ID token, to
+	// simulate a token issued for a different login attempt.
+	Nonce string
+}
+
+// grant is an authorization code waiting to be redeemed.
+type grant struct {
+	identity    Identity
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// Server is a mock OpenID Connect provider. Call SetIdentity before sending a user to the
+// authorization endpoint to choose who signs in.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu           sync.Mutex
+	key          *rsa.PrivateKey
+	identity     Identity
+	grants       map[string]grant
+	lastVerifier string
+}
+
+// NewServer starts a provider that issues ID tokens to clientID. Close it when the test ends.
+func NewServer(clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: generating key: " + err.Error())
+	}
+	s := &Server{ClientID: clientID, ClientSecret: clientSecret, key: key, grants: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Provider returns a Void provider configuration named name that signs in with s.
+func (s *Server) Provider(name string) *oidc.Provider {
+	return &oidc.Provider{
+		Name:         name,
+		DisplayName:  name,
+		Issuer:       s.URL,
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		Scopes:       []string{"email", "profile"},
+	}
+}
+
+// SetIdentity chooses who signs in at the authorization endpoint from now on.
+func (s *Server) SetIdentity(id Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = id
+}
+
+// LastVerifier returns the PKCE code verifier sent with the most recent token request.
+func (s *Server) LastVerifier() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastVerifier
+}
+
+// Authorize signs the current identity in for the authorization URL authURL, as a user's browser
+// would, and returns the callback URL the provider redirects back to.
+func (s *Server) Authorize(authURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return resp.Location()
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key: &s.key.PublicKey, KeyID: keyID, Algorithm: string(jose.RS256), Use: "sig",
+	}}})
+}
+
+// authorize issues a code for the current identity and redirects back to the client, carrying
+// the state through. Requests without an S256 PKCE challenge are refused.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("client_id") != s.ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.grants[code] = grant{identity: s.identity, nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), redirectURI: redirectURI.String()}
+	s.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", q.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token redeems a code for an access token and a signed ID token. The code is single-use, and the
+// redirect URI and PKCE verifier must match the authorization request.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	verifier := r.PostForm.Get("code_verifier")
+	s.mu.Lock()
+	s.lastVerifier = verifier
+	g, ok := s.grants[r.PostForm.Get("code")]
+	delete(s.grants, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(verifier))
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := s.sign(g)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// sign returns a signed ID token asserting g's identity.
+func (s *Server) sign(g grant) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"sub":   g.identity.Subject,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": g.nonce,
+		"email": g.identity.Email,
+	}
+	if g.identity.Nonce != "" {
+		claims["nonce"] = g.identity.Nonce
+	}
+	if g.identity.EmailVerified != nil {
+		claims["email_verified"] = g.identity.EmailVerified
+	}
+	if g.identity.PreferredUsername != "" {
+		claims["preferred_username"] = g.identity.PreferredUsername
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: s.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", keyID))
+	if err != nil {
+		return "", err
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return signed.CompactSerialize()
+}
+
+// randomString returns 16 random bytes, base64url-encoded, for codes and access tokens.
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("oidctest: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
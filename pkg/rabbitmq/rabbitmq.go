@@ -61,3 +61,16 @@ func ConsumeNotifications() (<-chan amqp.Delivery, error) {
 		nil,                   // args
 	)
 }
+
+// QueueDepth reports how many messages are waiting in the shout_notifications queue and how many
+// consumers are reading from it.
+func QueueDepth() (messages, consumers int, err error) {
+	if Channel == nil {
+		return 0, 0, amqp.ErrClosed
+	}
+	queue, err := Channel.QueueInspect("shout_notifications")
+	if err != nil {
+		return 0, 0, err
+	}
+	return queue.Messages, queue.Consumers, nil
+}
@@ -3,6 +3,8 @@ package session
 
 import (
 	"errors"
+	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
@@ -11,8 +13,30 @@ import (
 // Store is the global session store.
 var Store *session.Store
 
+// Lifetime is how long a session lasts after it was last saved.
+const Lifetime = 30 * 24 * time.Hour
+
+// SecureCookies reports whether session and CSRF cookies are marked Secure, so browsers only send
+// them over HTTPS. It is on unless SESSION_COOKIE_SECURE is "false"; browsers treat
+// http://localhost as secure, so local development works either way.
+func SecureCookies() bool {
+	return os.Getenv("SESSION_COOKIE_SECURE") != "false"
+}
+
+// NewConfig returns a session configuration with secure cookie defaults, keeping sessions in storage.
+func NewConfig(storage fiber.Storage) session.Config {
+	return session.Config{
+		Storage:        storage,
+		Expiration:     Lifetime,
+		KeyLookup:      "cookie:session_id",
+		CookieHTTPOnly: true,
+		CookieSecure:   SecureCookies(),
+		CookieSameSite: fiber.CookieSameSiteLaxMode,
+	}
+}
+
 // InitStore initializes the session store.
-// Optionally, you can pass a custom session.Config.
+// Optionally, you can pass a custom session.Config, such as one from NewConfig.
 func InitStore(config ...session.Config) {
 	if len(config) > 0 {
 		Store = session.New(config[0])
@@ -56,17 +80,156 @@ func GetUserID(c *fiber.Ctx) (uint, error) {
 	}
 }
 
-// SetUserID sets the user ID in the session and saves it.
-func SetUserID(c *fiber.Ctx, userID uint) error {
+// SetUserID logs userID in: it moves the session to a new ID, so an ID planted before login is
+// useless afterwards, and saves the user ID and the user's session version at login. It returns the
+// new session ID. The session stops being valid once the user's session version changes; see GetSessionVersion.
+func SetUserID(c *fiber.Ctx, userID, sessionVersion uint) (string, error) {
 	sess, err := GetSession(c)
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	if err := sess.Regenerate(); err != nil {
+		return "", err
+	}
 	sess.Set("user_id", userID)
+	sess.Set("session_version", sessionVersion)
+	sess.Delete("pending_user_id")
+	sess.Delete("pending_since")
+	sess.Delete("pending_attempts")
+	return sess.ID(), sess.Save()
+}
+
+// SetPendingUserID records a half-authenticated login: userID passed the password check but has
+// not yet completed their second factor. The pending login is not treated as logged in.
+func SetPendingUserID(c *fiber.Ctx, userID uint) error {
+	sess, err := GetSession(c)
+	if err != nil {
+		return err
+	}
+
+	sess.Delete("user_id")
+	sess.Set("pending_user_id", userID)
+	sess.Set("pending_since", time.Now().Unix())
+	sess.Set("pending_attempts", 0)
+	return sess.Save()
+}
+
+// GetPendingUserID retrieves the user ID of a half-authenticated login that started less than maxAge ago.
+func GetPendingUserID(c *fiber.Ctx, maxAge time.Duration) (uint, error) {
+	sess, err := GetSession(c)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, ok := sess.Get("pending_user_id").(uint)
+	since, _ := sess.Get("pending_since").(int64)
+	if !ok || time.Since(time.Unix(since, 0)) > maxAge {
+		return 0, errors.New("no pending login in session")
+	}
+	return userID, nil
+}
+
+// AddPendingAttempt counts a failed second-factor attempt for the pending login and returns the total so far.
+func AddPendingAttempt(c *fiber.Ctx) (int, error) {
+	sess, err := GetSession(c)
+	if err != nil {
+		return 0, err
+	}
+
+	attempts, _ := sess.Get("pending_attempts").(int)
+	attempts++
+	sess.Set("pending_attempts", attempts)
+	return attempts, sess.Save()
+}
+
+// OIDCLogin is a sign-in through an OpenID Connect provider that is waiting for the provider to
+// redirect back. Its values must match the callback, so only the browser that started it can finish it.
+type OIDCLogin struct {
+	Provider string
+	State    string
+	Nonce    string
+	Verifier string // PKCE code verifier
+}
+
+// SetOIDCLogin records an OpenID Connect sign-in in progress, replacing any earlier one.
+func SetOIDCLogin(c *fiber.Ctx, login OIDCLogin) error {
+	sess, err := GetSession(c)
+	if err != nil {
+		return err
+	}
+
+	sess.Set("oidc_provider", login.Provider)
+	sess.Set("oidc_state", login.State)
+	sess.Set("oidc_nonce", login.Nonce)
+	sess.Set("oidc_verifier", login.Verifier)
+	sess.Set("oidc_since", time.Now().Unix())
 	return sess.Save()
 }
 
+// TakeOIDCLogin retrieves and clears the OpenID Connect sign-in in progress, so each one can be
+// completed only once. Sign-ins started more than maxAge ago are rejected.
+func TakeOIDCLogin(c *fiber.Ctx, maxAge time.Duration) (OIDCLogin, error) {
+	sess, err := GetSession(c)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	login := OIDCLogin{}
+	login.Provider, _ = sess.Get("oidc_provider").(string)
+	login.State, _ = sess.Get("oidc_state").(string)
+	login.Nonce, _ = sess.Get("oidc_nonce").(string)
+	login.Verifier, _ = sess.Get("oidc_verifier").(string)
+	since, _ := sess.Get("oidc_since").(int64)
+	for _, key := range []string{"oidc_provider", "oidc_state", "oidc_nonce", "oidc_verifier", "oidc_since"} {
+		sess.Delete(key)
+	}
+	if err := sess.Save(); err != nil {
+		return OIDCLogin{}, err
+	}
+
+	if login.State == "" || time.Since(time.Unix(since, 0)) > maxAge {
+		return OIDCLogin{}, errors.New("no OIDC login in session")
+	}
+	return login, nil
+}
+
+// ID returns the current session's ID.
+func ID(c *fiber.Ctx) (string, error) {
+	sess, err := GetSession(c)
+	if err != nil {
+		return "", err
+	}
+	return sess.ID(), nil
+}
+
+// Revoke ends the session with the given ID, wherever it is being used.
+func Revoke(id string) error {
+	if Store == nil {
+		return errors.New("session store is not initialized")
+	}
+	return Store.Delete(id)
+}
+
+// GetSessionVersion retrieves the session version the user had when this session was created.
+func GetSessionVersion(c *fiber.Ctx) (uint, error) {
+	sess, err := GetSession(c)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := sess.Get("session_version").(type) {
+	case uint:
+		return v, nil
+	case float64:
+		return uint(v), nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, errors.New("invalid session version type in session")
+	}
+}
+
 // DestroySession destroys the current session.
 func DestroySession(c *fiber.Ctx) error {
 	sess, err := GetSession(c)
@@ -0,0 +1,96 @@
+// session/sql_storage.go
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sqlEntry is one stored session.
+type sqlEntry struct {
+	Key       string `gorm:"primaryKey"`
+	Value     []byte
+	ExpiresAt int64 `gorm:"index"` // Unix seconds; zero never expires
+}
+
+// TableName stores sessions in the "sessions" table.
+func (sqlEntry) TableName() string {
+	return "sessions"
+}
+
+// SQLStorage is a fiber.Storage that keeps sessions in a database table through GORM, so they
+// survive restarts and can be shared by several server instances using the same database.
+// Any other fiber.Storage, such as Redis, can be passed to NewConfig instead.
+type SQLStorage struct {
+	db   *gorm.DB
+	done chan struct{}
+}
+
+// NewSQLStorage creates the sessions table if needed and returns a storage backed by it.
+// Expired sessions are deleted every gcInterval.
+func NewSQLStorage(db *gorm.DB, gcInterval time.Duration) (*SQLStorage, error) {
+	if err := db.AutoMigrate(&sqlEntry{}); err != nil {
+		return nil, err
+	}
+	s := &SQLStorage{db: db, done: make(chan struct{})}
+	go s.gc(gcInterval)
+	return s, nil
+}
+
+// Get returns the value stored for key, or nil if there is none or it has expired.
+func (s *SQLStorage) Get(key string) ([]byte, error) {
+	var entry sqlEntry
+	err := s.db.Where("key = ? AND (expires_at = 0 OR expires_at > ?)", key, time.Now().Unix()).
+		Limit(1).Find(&entry).Error
+	if err != nil || entry.Key == "" {
+		return nil, err
+	}
+	return entry.Value, nil
+}
+
+// Set stores val under key for exp, or forever if exp is zero.
+func (s *SQLStorage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+	entry := sqlEntry{Key: key, Value: val}
+	if exp > 0 {
+		entry.ExpiresAt = time.Now().Add(exp).Unix()
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at"}),
+	}).Create(&entry).Error
+}
+
+// Delete removes key.
+func (s *SQLStorage) Delete(key string) error {
+	return s.db.Where("key = ?", key).Delete(&sqlEntry{}).Error
+}
+
+// Reset removes every session.
+func (s *SQLStorage) Reset() error {
+	return s.db.Where("1 = 1").Delete(&sqlEntry{}).Error
+}
+
+// Close stops the expiry loop. The database connection is left open.
+func (s *SQLStorage) Close() error {
+	close(s.done)
+	return nil
+}
+
+// gc periodically deletes expired sessions until Close is called.
+func (s *SQLStorage) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.db.Where("expires_at > 0 AND expires_at <= ?", time.Now().Unix()).Delete(&sqlEntry{})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 appendix B SHA-1 seed, "12345678901234567890", base32-encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 checks Code against the RFC 6238 SHA-1 test vectors. The RFC lists eight-digit
+// codes; six-digit codes are their last six digits.
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if want := tt.want[len(tt.want)-Digits:]; got != want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowerCaseSecret(t *testing.T) {
+	upper, _ := Code(rfcSecret, 1)
+	lower, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil || lower != upper {
+		t.Errorf("Code with a lower-case secret = %q, %v; want %q", lower, err, upper)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code with an invalid secret: want an error")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, _ := Code(rfcSecret, current+offset)
+		step, ok := Validate(rfcSecret, code, now)
+		wantOK := offset >= -Skew && offset <= Skew
+		if ok != wantOK {
+			t.Errorf("code from step %+d: ok = %v, want %v", offset, ok, wantOK)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code from step %+d: matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateFormatting(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, Step(now))
+	for _, input := range []string{code, " " + code + " ", code[:3] + " " + code[3:]} {
+		if _, ok := Validate(rfcSecret, input, now); !ok {
+			t.Errorf("Validate(%q) rejected a current code", input)
+		}
+	}
+	for _, input := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := Validate(rfcSecret, input, now); ok {
+			t.Errorf("Validate(%q) accepted a malformed code", input)
+		}
+	}
+}